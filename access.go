@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// WorkspaceAccess is the outcome of probing one workspace's meta endpoint
+// with the configured credentials.
+type WorkspaceAccess struct {
+	Workspace  string `json:"workspace"`
+	Accessible bool   `json:"accessible"`
+	// Status is the response status, or the request error
+	Status string `json:"status"`
+	// StatusCode is the response status code, zero if the request failed
+	StatusCode int `json:"status_code,omitempty"`
+}
+
+// checkAccess probes the meta endpoint of every workspace with a HEAD
+// request, falling back to GET where HEAD is not supported, so the token's
+// scope is known before a full scan. Up to concurrency workspaces are probed
+// in parallel; those not reached before ctx is done are reported as such.
+func checkAccess(ctx context.Context, client *Client, baseURL string, workspaces []Workspace, metaValues url.Values, concurrency int) []WorkspaceAccess {
+	access := make([]WorkspaceAccess, len(workspaces))
+	for index, workspace := range workspaces {
+		access[index] = WorkspaceAccess{Workspace: workspace.Name, Status: errNotReached.Error()}
+	}
+
+	forEachLimit(ctx, len(workspaces), concurrency, func(ctx context.Context, index int) error {
+		metaURL := workspaceMetaURL(baseURL, workspaces[index].Name, metaValues)
+		resp, err := client.do(ctx, "HEAD", metaURL)
+		if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+			resp.Body.Close()
+			resp, err = client.do(ctx, "GET", metaURL)
+		}
+		if err != nil {
+			access[index].Status = err.Error()
+			return nil
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		access[index].Accessible = resp.StatusCode < 400
+		access[index].Status = resp.Status
+		access[index].StatusCode = resp.StatusCode
+		return nil
+	})
+	return access
+}
+
+// writeAccess renders the probe results as a table followed by a summary,
+// or as JSON when format is json.
+func writeAccess(w io.Writer, format string, quiet bool, access []WorkspaceAccess) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(access)
+	}
+
+	if !quiet {
+		fmt.Fprintln(w, "Workspace Access:")
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Workspace Name", "Access", "Status"})
+	table.SetAutoWrapText(false)
+	accessible := 0
+	for _, workspace := range access {
+		label := "error"
+		if workspace.Accessible {
+			label = "ok"
+			accessible++
+		} else if workspace.StatusCode == http.StatusForbidden {
+			label = "forbidden"
+		}
+		table.Append([]string{workspace.Workspace, label, workspace.Status})
+	}
+	table.Render()
+	fmt.Fprintf(w, "%d of %d workspaces accessible\n", accessible, len(access))
+	return nil
+}
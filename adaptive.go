@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// adaptiveTransport limits the number of requests in flight with an AIMD
+// controller: the limit grows by one per window of healthy responses and is
+// halved on 429 or 503 responses, transport errors, or when the smoothed
+// latency rises to twice the lowest latency seen.
+type adaptiveTransport struct {
+	base http.RoundTripper
+	max  float64
+	// w receives a line for every limit decrease, if not nil
+	w io.Writer
+
+	mu           sync.Mutex
+	limit        float64
+	inFlight     int
+	wake         chan struct{}
+	minLatency   time.Duration
+	smoothed     time.Duration
+	lastDecrease time.Time
+}
+
+// newAdaptiveTransport starts at one request in flight and never allows
+// more than max.
+func newAdaptiveTransport(base http.RoundTripper, max int, w io.Writer) *adaptiveTransport {
+	return &adaptiveTransport{base: base, max: float64(max), w: w, limit: 1, wake: make(chan struct{})}
+}
+
+func (t *adaptiveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Wait for a free slot under the current limit
+	for {
+		t.mu.Lock()
+		if t.inFlight < int(t.limit) {
+			t.inFlight++
+			t.mu.Unlock()
+			break
+		}
+		wake := t.wake
+		t.mu.Unlock()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-wake:
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	latency := time.Since(start)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inFlight--
+	switch {
+	case err != nil && req.Context().Err() == nil:
+		t.decrease("request error")
+	case err == nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable):
+		t.decrease(resp.Status)
+	case err == nil:
+		t.observe(latency)
+	}
+
+	// Let the waiting requests recheck the limit
+	close(t.wake)
+	t.wake = make(chan struct{})
+	return resp, err
+}
+
+// observe records the latency of a healthy response and adjusts the limit.
+func (t *adaptiveTransport) observe(latency time.Duration) {
+	if t.minLatency == 0 || latency < t.minLatency {
+		t.minLatency = latency
+	}
+	if t.smoothed == 0 {
+		t.smoothed = latency
+	} else {
+		t.smoothed = (4*t.smoothed + latency) / 5
+	}
+
+	if t.smoothed > 2*t.minLatency {
+		t.decrease(fmt.Sprintf("latency %s", t.smoothed.Round(time.Millisecond)))
+		return
+	}
+	t.limit = min(t.max, t.limit+1/t.limit)
+}
+
+// decrease halves the limit, at most once per smoothed round trip so a
+// burst of failures from the same window only counts once.
+func (t *adaptiveTransport) decrease(reason string) {
+	if time.Since(t.lastDecrease) < t.smoothed {
+		return
+	}
+	t.lastDecrease = time.Now()
+
+	previous := int(t.limit)
+	t.limit = max(1, t.limit/2)
+	if t.w != nil && int(t.limit) != previous {
+		fmt.Fprintf(t.w, "Adaptive concurrency %d -> %d (%s)\n", previous, int(t.limit), reason)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+)
+
+// appendChangeLog appends one watch cycle to the change log at path,
+// creating it if needed: a line with the time and the number of changes,
+// followed by one indented "workspace field old -> new" line per change.
+func appendChangeLog(path string, at time.Time, changes []CountChange) error {
+	var entry bytes.Buffer
+	switch len(changes) {
+	case 0:
+		fmt.Fprintf(&entry, "%s no changes\n", at.UTC().Format(time.RFC3339))
+	case 1:
+		fmt.Fprintf(&entry, "%s 1 change\n", at.UTC().Format(time.RFC3339))
+	default:
+		fmt.Fprintf(&entry, "%s %d changes\n", at.UTC().Format(time.RFC3339), len(changes))
+	}
+	for _, change := range changes {
+		fmt.Fprintf(&entry, "  %s %s %d -> %d (%+d)\n", change.Workspace, change.Field, change.Baseline, change.Current, change.Delta)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(entry.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// reportChecksum returns a SHA-256 of the per-workspace counts that does not
+// depend on the order workspaces or fields were collected in, so equal
+// checksums across runs mean no count changed.
+func reportChecksum(report Report) (string, error) {
+	canonical := struct {
+		Workspaces map[string]map[string]int `json:"workspaces"`
+		Default    map[string]int            `json:"default,omitempty"`
+	}{Workspaces: workspaceCounts(report)}
+	if report.Default != nil {
+		canonical.Default = report.Default.Meta.Counts
+	}
+
+	// encoding/json writes map keys sorted, which makes the encoding stable
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// withBasePath appends the path prefix to the admin address, so that
+// "http://kong:8001/" and "/kong-admin/" give "http://kong:8001/kong-admin"
+// and the paths appended to it never contain a double slash.
+func withBasePath(addr string, basePath string) (string, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%q is not an absolute URL", addr)
+	}
+	u.Path = strings.TrimSuffix(path.Join("/", u.Path, basePath), "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// Client sends requests to the Kong Admin API with a common set of headers.
+type Client struct {
+	HTTP    *http.Client
+	Headers http.Header
+	// DataField is the top-level field holding the array in list responses,
+	// "data" for Kong itself
+	DataField string
+	// MaxRequests caps the number of requests sent, zero means no limit
+	MaxRequests int64
+	requests    atomic.Int64
+	// Retries is how many times a request answered with a 5xx or one of
+	// RetryStatus is repeated before giving up
+	Retries     int
+	RetryStatus map[int]bool
+	// RetryBudget caps the retries of all requests together, zero means no
+	// limit
+	RetryBudget int64
+	retries     atomic.Int64
+}
+
+// StatusError is returned for responses with an error status.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return "unexpected status " + e.Status
+}
+
+// isNotFound reports whether err is a 404 response.
+func isNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound
+}
+
+// errRequestBudget is returned instead of sending a request once the client
+// has sent MaxRequests requests.
+var errRequestBudget = errors.New("request budget exhausted")
+
+// AuthOptions are the authentication schemes applied to every request. They
+// compose: basic auth and bearer set Authorization, the token sets
+// Kong-Admin-Token, and all can be combined with arbitrary --headers. When
+// two would set the same header, explicit --headers win over the bearer
+// token, which wins over basic auth.
+type AuthOptions struct {
+	// BasicAuth is "user:password"
+	BasicAuth string
+	Bearer    string
+	Token     string
+}
+
+// header returns the headers for the configured schemes, lowest precedence
+// first so later schemes replace earlier ones.
+func (a AuthOptions) header() (http.Header, error) {
+	header := http.Header{}
+	if a.BasicAuth != "" {
+		user, password, ok := strings.Cut(a.BasicAuth, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid basic auth, expected 'user:password'")
+		}
+		req := &http.Request{Header: header}
+		req.SetBasicAuth(user, password)
+	}
+	if a.Bearer != "" {
+		header.Set("Authorization", "Bearer "+a.Bearer)
+	}
+	if a.Token != "" {
+		header.Set("Kong-Admin-Token", a.Token)
+	}
+	return header, nil
+}
+
+// newClient builds a Client from the authentication options and
+// "Name: value" header strings that follows at most maxRedirects redirects.
+func newClient(headers []string, auth AuthOptions, maxRedirects int) (*Client, error) {
+	merged, err := auth.header()
+	if err != nil {
+		return nil, err
+	}
+
+	// Explicit headers replace any header set by an auth scheme
+	parsed, err := parseHeaders(headers)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range parsed {
+		merged[name] = values
+	}
+
+	client := &Client{
+		HTTP:    &http.Client{},
+		Headers: merged,
+	}
+	client.HTTP.CheckRedirect = client.checkRedirect(maxRedirects)
+	return client, nil
+}
+
+// checkRedirect limits the number of redirects and re-applies the configured
+// headers to each redirected request. Go drops sensitive headers such as
+// Authorization when a redirect crosses hosts, which would otherwise break
+// authentication behind a load balancer that redirects to a canonical host.
+func (c *Client) checkRedirect(maxRedirects int) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		for name := range c.Headers {
+			req.Header.Del(name)
+		}
+		c.applyHeaders(req)
+		return nil
+	}
+}
+
+// applyHeaders adds the configured headers to req.
+func (c *Client) applyHeaders(req *http.Request) {
+	for name, values := range c.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+}
+
+// parseHeaders converts "Name: value" strings into an http.Header. Values may
+// themselves contain colons, and repeating a name adds another value rather
+// than replacing the previous one.
+func parseHeaders(headers []string) (http.Header, error) {
+	parsed := http.Header{}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, expected 'Name: value'", header)
+		}
+		parsed.Add(name, strings.TrimSpace(value))
+	}
+	return parsed, nil
+}
+
+// do sends a request with the client's headers applied, retrying retryable
+// statuses up to c.Retries times. The caller must close the response body.
+func (c *Client) do(ctx context.Context, method string, url string) (*http.Response, error) {
+	counted := ctx.Value(uncountedKey{}) == nil
+	for attempt := 0; ; attempt++ {
+		// Refuse to go over the request budget if specified
+		if counted {
+			if sent := c.requests.Add(1); c.MaxRequests > 0 && sent > c.MaxRequests {
+				return nil, errRequestBudget
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// Add headers if provided
+		c.applyHeaders(req)
+
+		resp, err := c.HTTP.Do(req)
+		if err != nil || attempt >= c.Retries || !c.retryable(resp.StatusCode) {
+			return resp, err
+		}
+
+		// Stop retrying once the run's retry budget is spent
+		if c.RetryBudget > 0 && c.retries.Add(1) > c.RetryBudget {
+			return resp, nil
+		}
+
+		// Drain the body so the connection can be reused, then wait
+		delay := retryDelay(attempt, resp)
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type uncountedKey struct{}
+
+// uncounted marks the requests made with the returned context as outside
+// MaxRequests and Requests, for probes that are not part of the scan itself.
+func uncounted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, uncountedKey{}, true)
+}
+
+// Requests returns the number of requests attempted so far, retries
+// included, leaving out uncounted ones.
+func (c *Client) Requests() int64 {
+	return c.requests.Load()
+}
+
+// get sends a GET request and returns the response body. Error statuses are
+// returned as errors.
+func (c *Client) get(ctx context.Context, url string) ([]byte, error) {
+	resp, err := c.do(ctx, "GET", url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// errPageLimit is returned along with the workspaces listed so far when the
+// listing still had more pages after maxPages.
+var errPageLimit = errors.New("page limit reached, the workspace list may be incomplete")
+
+// getWorkspaces lists the workspaces, following Kong's offset pagination for
+// at most maxPages pages (zero means no limit). The whole listing is bounded
+// by timeout unless it is zero.
+func getWorkspaces(ctx context.Context, client *Client, workspacesURL string, timeout time.Duration, maxPages int) ([]Workspace, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	workspaces := make([]Workspace, 0)
+	offset := ""
+	for page := 1; ; page++ {
+		query := url.Values{"size": {"1000"}}
+		if offset != "" {
+			query.Set("offset", offset)
+		}
+
+		body, err := client.get(ctx, workspacesURL+"?"+query.Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		var cursor entityPage
+		if err := json.Unmarshal(body, &cursor); err != nil {
+			return nil, err
+		}
+		var data []Workspace
+		if err := decodeList(body, client.DataField, &data); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, data...)
+
+		if cursor.Offset == "" {
+			return workspaces, nil
+		}
+		if maxPages > 0 && page >= maxPages {
+			return workspaces, errPageLimit
+		}
+		offset = cursor.Offset
+	}
+}
+
+// decodeList decodes the array under field of a list response into v.
+func decodeList(body []byte, field string, v any) error {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return err
+	}
+
+	data, ok := response[field]
+	if !ok {
+		return fmt.Errorf("list response has no %q field", field)
+	}
+	return json.Unmarshal(data, v)
+}
+
+func getMetadata(ctx context.Context, client *Client, metaURL string) (Metadata, error) {
+	body, err := client.get(ctx, metaURL)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var metadata Metadata
+	err = json.Unmarshal(body, &metadata)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	return metadata, nil
+}
+
+// entityPage is the pagination cursor of a Kong entity listing.
+type entityPage struct {
+	Offset string `json:"offset"`
+}
+
+// listEntities returns every entity of one type in a workspace, following
+// Kong's offset pagination until the last page.
+func listEntities(ctx context.Context, client *Client, baseURL string, workspace string, entity string) ([]map[string]any, error) {
+	entityURL := baseURL + "/workspaces/" + workspace + "/" + entity
+
+	entities := make([]map[string]any, 0)
+	offset := ""
+	for {
+		query := url.Values{"size": {"1000"}}
+		if offset != "" {
+			query.Set("offset", offset)
+		}
+
+		body, err := client.get(ctx, entityURL+"?"+query.Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		var page entityPage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		var data []map[string]any
+		if err := decodeList(body, client.DataField, &data); err != nil {
+			return nil, err
+		}
+		entities = append(entities, data...)
+
+		if page.Offset == "" {
+			return entities, nil
+		}
+		offset = page.Offset
+	}
+}
+
+// stringField returns a string attribute of a listed entity, or "".
+func stringField(entity map[string]any, name string) string {
+	value, _ := entity[name].(string)
+	return value
+}
+
+// boolField returns a boolean attribute of a listed entity, or false.
+func boolField(entity map[string]any, name string) bool {
+	value, _ := entity[name].(bool)
+	return value
+}
+
+// stringsField returns a string array attribute of a listed entity, such as
+// its tags.
+func stringsField(entity map[string]any, name string) []string {
+	values, _ := entity[name].([]any)
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestClientSendsAllHeaders(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]http.Header)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/workspaces":
+			w.Write([]byte(`{"data": [{"name": "x"}], "next": null}`))
+		case "/workspaces/x/meta":
+			w.Write([]byte(`{"counts": {"services": 1}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	headers := []string{"X-Team: platform", "X-Request-Source: meta", "X-Tenant: eu", "X-Tenant: us"}
+	client, err := newClient(headers, AuthOptions{Token: "secret"}, 0)
+	if err != nil {
+		t.Fatalf("newClient: %v", err)
+	}
+	client.DataField = "data"
+
+	ctx := context.Background()
+	workspaces, err := getWorkspaces(ctx, client, server.URL+"/workspaces", 0, 0)
+	if err != nil {
+		t.Fatalf("getWorkspaces: %v", err)
+	}
+	if len(workspaces) != 1 || workspaces[0].Name != "x" {
+		t.Fatalf("getWorkspaces = %+v, want one workspace named x", workspaces)
+	}
+	if _, err := getMetadata(ctx, client, server.URL+"/workspaces/x/meta"); err != nil {
+		t.Fatalf("getMetadata: %v", err)
+	}
+
+	// A repeated name carries every value instead of the last one
+	want := map[string][]string{
+		"X-Team":           {"platform"},
+		"X-Request-Source": {"meta"},
+		"X-Tenant":         {"eu", "us"},
+		"Kong-Admin-Token": {"secret"},
+	}
+	for _, path := range []string{"/workspaces", "/workspaces/x/meta"} {
+		header, ok := received[path]
+		if !ok {
+			t.Errorf("%s was not requested", path)
+			continue
+		}
+		for name, values := range want {
+			if got := header.Values(name); !slices.Equal(got, values) {
+				t.Errorf("%s: header %s = %q, want %q", path, name, got, values)
+			}
+		}
+	}
+}
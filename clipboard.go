@@ -0,0 +1,16 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/atotto/clipboard"
+)
+
+// ansiEscape matches the color sequences used in terminal output.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// copyToClipboard places text on the system clipboard without any terminal
+// colors, so it pastes cleanly into chat and documents.
+func copyToClipboard(text string) error {
+	return clipboard.WriteAll(ansiEscape.ReplaceAllString(text, ""))
+}
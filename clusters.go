@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ClusterReport is the outcome of scanning one of several clusters. Either
+// Report or Error is set.
+type ClusterReport struct {
+	Address string  `json:"address"`
+	Report  *Report `json:"report,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	// Failures are the workspaces of the cluster that could not be collected
+	Failures []ClusterFailure `json:"failures,omitempty"`
+	// failures keeps the same workspaces for the errors table
+	failures *CollectError
+}
+
+// ClusterFailure is a workspace whose metadata could not be collected.
+type ClusterFailure struct {
+	Workspace string `json:"workspace"`
+	Error     string `json:"error"`
+}
+
+// clusterScanner collects the report of the cluster at addr.
+type clusterScanner func(ctx context.Context, addr string) (Report, *CollectError, error)
+
+// scanClusters runs scan for every address, at most concurrency at a time,
+// and returns the outcomes in the order of addrs. A failing cluster does not
+// stop the others.
+func scanClusters(ctx context.Context, addrs []string, concurrency int, scan clusterScanner) []ClusterReport {
+	clusters := make([]ClusterReport, len(addrs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			clusters[i].Address = addr
+			report, failures, err := scan(ctx, addr)
+			if err != nil {
+				clusters[i].Error = err.Error()
+				return
+			}
+			clusters[i].Report = &report
+			if failures != nil {
+				clusters[i].failures = failures
+				for _, failure := range failures.Failures {
+					clusters[i].Failures = append(clusters[i].Failures, ClusterFailure{Workspace: failure.Workspace, Error: failure.Err.Error()})
+				}
+			}
+		}(i, addr)
+	}
+	wg.Wait()
+	return clusters
+}
+
+// writeClusterReports renders one section per cluster, or a JSON array of
+// the outcomes when format is json. Either way the failed workspaces of each
+// cluster are listed on stderr after the output, as in a single-cluster run.
+func writeClusterReports(w io.Writer, format string, opts RenderOptions, clusters []ClusterReport) error {
+	defer func() {
+		for _, cluster := range clusters {
+			if cluster.failures != nil {
+				fmt.Fprintf(os.Stderr, "=== %s ===\n", cluster.Address)
+				printErrorsTable(os.Stderr, cluster.failures)
+			}
+		}
+	}()
+
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(clusters)
+	}
+
+	for i, cluster := range clusters {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "=== %s ===\n", cluster.Address)
+		if cluster.Report == nil {
+			fmt.Fprintln(w, "Error:", cluster.Error)
+			continue
+		}
+		opts.ClusterAddr = cluster.Address
+		if err := writeOutput(w, format, opts, *cluster.Report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
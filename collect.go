@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// WorkspaceError records why the metadata of one workspace could not be
+// collected.
+type WorkspaceError struct {
+	Workspace string
+	Err       error
+}
+
+func (e WorkspaceError) Error() string {
+	return fmt.Sprintf("workspace %s: %v", e.Workspace, e.Err)
+}
+
+func (e WorkspaceError) Unwrap() error {
+	return e.Err
+}
+
+// CollectError accumulates the per-workspace failures of a collection run.
+// The workspaces that succeeded are still returned alongside it.
+type CollectError struct {
+	Failures []WorkspaceError
+}
+
+func (e *CollectError) Error() string {
+	if len(e.Failures) == 1 {
+		return "failed to collect metadata for 1 workspace"
+	}
+	return fmt.Sprintf("failed to collect metadata for %d workspaces", len(e.Failures))
+}
+
+func (e *CollectError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure
+	}
+	return errs
+}
+
+// withoutNotFound returns the failures other than a 404 from the meta
+// endpoint, or nil if there are none, along with the number of 404s removed.
+func (e *CollectError) withoutNotFound() (*CollectError, int) {
+	remaining := &CollectError{}
+	notFound := 0
+	for _, failure := range e.Failures {
+		if isNotFound(failure.Err) {
+			notFound++
+			continue
+		}
+		remaining.Failures = append(remaining.Failures, failure)
+	}
+
+	if len(remaining.Failures) == 0 {
+		return nil, notFound
+	}
+	return remaining, notFound
+}
+
+// errNotReached marks workspaces skipped because the run's deadline passed
+// before their metadata was requested.
+var errNotReached = errors.New("not reached before the deadline")
+
+// CollectOptions controls how collectMetadata fetches metadata.
+type CollectOptions struct {
+	// MetaValues are appended as query parameters to each metadata URL
+	MetaValues url.Values
+	// Concurrency is the number of parallel metadata requests
+	Concurrency int
+	// PerRequestTimeout caps each metadata request, zero means no limit
+	PerRequestTimeout time.Duration
+	// Raw keeps the unparsed response bodies in WorkspaceMetadata.Raw
+	Raw bool
+	// CountFallback counts the entities of workspaces without a meta
+	// endpoint from the totals reported by their list endpoints
+	CountFallback bool
+	// DetailConcurrency is the number of parallel fallback count requests
+	// within one workspace
+	DetailConcurrency int
+}
+
+// fallbackEntities are the entity types counted when falling back from the
+// meta endpoint.
+var fallbackEntities = []string{"services", "routes", "plugins", "consumers", "upstreams", "certificates"}
+
+// workspaceMetaURL returns the meta endpoint of the named workspace with the
+// --meta-query values appended.
+func workspaceMetaURL(baseURL string, name string, metaValues url.Values) string {
+	metaURL := baseURL + "/workspaces/" + name + "/meta"
+	if len(metaValues) > 0 {
+		metaURL += "?" + metaValues.Encode()
+	}
+	return metaURL
+}
+
+// collectMetadata fetches the metadata of every workspace using up to
+// opts.Concurrency parallel requests. Workspaces whose metadata cannot be
+// fetched are left out of the result and reported in a *CollectError. Once
+// ctx is done no new requests are issued and the remaining workspaces are
+// reported as not reached.
+func collectMetadata(ctx context.Context, client *Client, baseURL string, workspaces []Workspace, opts CollectOptions) ([]WorkspaceMetadata, error) {
+	// Results are stored by listing index so goroutines never share a slot
+	results := make([]*WorkspaceMetadata, len(workspaces))
+	failures := make([]error, len(workspaces))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				if ctx.Err() != nil {
+					failures[index] = errNotReached
+					continue
+				}
+
+				workspace := workspaces[index]
+				metaURL := workspaceMetaURL(baseURL, workspace.Name, opts.MetaValues)
+
+				result, err := fetchWorkspaceMetadata(ctx, client, workspace.Name, metaURL, opts)
+				if err != nil && opts.CountFallback && !opts.Raw && isNotFound(err) {
+					result, err = countFromTotals(ctx, client, baseURL, workspace.Name, opts.DetailConcurrency)
+				}
+				if err != nil {
+					failures[index] = err
+					continue
+				}
+				result.ID = workspace.ID
+				results[index] = &result
+			}
+		}()
+	}
+
+	for index := range workspaces {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	workspaceMetadataList := make([]WorkspaceMetadata, 0, len(workspaces))
+	collectErr := &CollectError{}
+	for index, result := range results {
+		if result != nil {
+			workspaceMetadataList = append(workspaceMetadataList, *result)
+		}
+		if failures[index] != nil {
+			collectErr.Failures = append(collectErr.Failures, WorkspaceError{
+				Workspace: workspaces[index].Name,
+				Err:       failures[index],
+			})
+		}
+	}
+
+	if len(collectErr.Failures) > 0 {
+		return workspaceMetadataList, collectErr
+	}
+	return workspaceMetadataList, nil
+}
+
+// fetchWorkspaceMetadata requests the metadata of one workspace, bounded by
+// opts.PerRequestTimeout. With opts.Raw the body is kept as-is instead of
+// being parsed.
+func fetchWorkspaceMetadata(ctx context.Context, client *Client, name string, metaURL string, opts CollectOptions) (WorkspaceMetadata, error) {
+	if opts.PerRequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.PerRequestTimeout)
+		defer cancel()
+	}
+
+	if opts.Raw {
+		body, err := client.get(ctx, metaURL)
+		if err != nil {
+			return WorkspaceMetadata{}, err
+		}
+		return WorkspaceMetadata{WorkspaceName: name, Raw: body}, nil
+	}
+
+	meta, err := getMetadata(ctx, client, metaURL)
+	if err != nil {
+		return WorkspaceMetadata{}, err
+	}
+	return WorkspaceMetadata{WorkspaceName: name, Meta: meta}, nil
+}
+
+// countFromTotals builds the metadata of a workspace from the total each
+// entity list endpoint reports when asked for size=0, which avoids paging
+// through every entity. Up to detailConcurrency entity types are counted in
+// parallel.
+func countFromTotals(ctx context.Context, client *Client, baseURL string, name string, detailConcurrency int) (WorkspaceMetadata, error) {
+	// Totals are stored by entity index, then collected into the counts
+	totals := make([]int, len(fallbackEntities))
+	err := forEachLimit(ctx, len(fallbackEntities), detailConcurrency, func(ctx context.Context, index int) error {
+		entity := fallbackEntities[index]
+		body, err := client.get(ctx, baseURL+"/workspaces/"+name+"/"+entity+"?size=0")
+		if err != nil {
+			return fmt.Errorf("counting %s: %w", entity, err)
+		}
+
+		var page struct {
+			Total *int `json:"total"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("counting %s: %w", entity, err)
+		}
+		if page.Total == nil {
+			return fmt.Errorf("counting %s: the list endpoint reports no total", entity)
+		}
+		totals[index] = *page.Total
+		return nil
+	})
+	if err != nil {
+		return WorkspaceMetadata{}, err
+	}
+
+	counts := make(map[string]int, len(fallbackEntities))
+	for index, entity := range fallbackEntities {
+		counts[entity] = totals[index]
+	}
+	return WorkspaceMetadata{WorkspaceName: name, Meta: Metadata{Counts: counts}}, nil
+}
+
+// printErrorsTable lists each workspace that failed and why.
+func printErrorsTable(w io.Writer, collectErr *CollectError) {
+	fmt.Fprintln(w, "Errors:")
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Workspace Name", "Error"})
+	table.SetAutoWrapText(false)
+	for _, failure := range collectErr.Failures {
+		table.Append([]string{failure.Workspace, failure.Err.Error()})
+	}
+	table.Render()
+}
+
+// sortWorkspaceMetadata orders the list by workspace name so output is
+// deterministic across runs. The "name" mode compares numeric runs by value
+// (ws2 before ws10); "lexical" compares names byte by byte; "none" keeps the
+// order in which the workspaces were listed.
+func sortWorkspaceMetadata(metadataList []WorkspaceMetadata, mode string) error {
+	var less func(a, b string) bool
+	switch mode {
+	case "none":
+		return nil
+	case "name":
+		less = naturalLess
+	case "lexical":
+		less = func(a, b string) bool { return a < b }
+	default:
+		return fmt.Errorf("unknown workspace sort %q", mode)
+	}
+
+	sort.SliceStable(metadataList, func(i, j int) bool {
+		return less(metadataList[i].WorkspaceName, metadataList[j].WorkspaceName)
+	})
+	return nil
+}
+
+// naturalLess compares two strings treating runs of digits as numbers, so
+// "ws2" sorts before "ws10".
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+		if aDigits != "" && bDigits != "" {
+			// Compare numerically without overflow: fewer significant
+			// digits means a smaller number
+			aTrimmed, bTrimmed := strings.TrimLeft(aDigits, "0"), strings.TrimLeft(bDigits, "0")
+			if len(aTrimmed) != len(bTrimmed) {
+				return len(aTrimmed) < len(bTrimmed)
+			}
+			if aTrimmed != bTrimmed {
+				return aTrimmed < bTrimmed
+			}
+			if len(aDigits) != len(bDigits) {
+				return len(aDigits) < len(bDigits)
+			}
+			a, b = a[len(aDigits):], b[len(bDigits):]
+			continue
+		}
+
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// leadingDigits returns the run of ASCII digits at the start of s.
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// configSetting is one resolved setting shown by --print-config, with the
+// source it was taken from.
+type configSetting struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// flagSetting describes the current value of the named flag. Flags that
+// were not given on the command line report their default.
+func flagSetting(name string, set map[string]bool) configSetting {
+	source := "default"
+	if set[name] {
+		source = "flag"
+	}
+	return configSetting{Name: name, Value: flag.Lookup(name).Value.String(), Source: source}
+}
+
+// headerSettings describes the headers sent with every request, as merged
+// from --headers and the auth settings, hiding the values of credentials.
+func headerSettings(header http.Header) []configSetting {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	settings := make([]configSetting, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if sensitiveHeaders[name] {
+			value = redact(value)
+		}
+		settings = append(settings, configSetting{Name: "header " + name, Value: value, Source: "headers and auth"})
+	}
+	return settings
+}
+
+// redact hides a secret while still showing whether it is set.
+func redact(secret string) string {
+	if secret == "" {
+		return "(none)"
+	}
+	return "[REDACTED]"
+}
+
+// printConfig writes the settings as aligned columns.
+func printConfig(w io.Writer, settings []configSetting) {
+	fmt.Fprintln(w, "Effective configuration:")
+	table := &alignedTable{w: w, header: []string{"SETTING", "VALUE", "SOURCE"}, left: true}
+	for _, setting := range settings {
+		value := setting.Value
+		if value == "" {
+			value = "(none)"
+		}
+		table.Append([]string{setting.Name, value, setting.Source})
+	}
+	table.Render()
+}
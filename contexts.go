@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KongContext is a named admin API target, similar to a kubeconfig context.
+type KongContext struct {
+	Name     string `json:"name"`
+	KongAddr string `json:"kong_addr"`
+	Token    string `json:"token"`
+}
+
+// ContextsFile is the document read by --context.
+type ContextsFile struct {
+	Contexts []KongContext `json:"contexts"`
+}
+
+// defaultContextsFile returns $KONG_CONTEXTS, or ~/.kong/contexts.json.
+func defaultContextsFile() string {
+	if path := os.Getenv("KONG_CONTEXTS"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kong", "contexts.json")
+}
+
+// loadContext reads the contexts file at path and returns the named entry.
+func loadContext(path string, name string) (KongContext, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return KongContext{}, err
+	}
+
+	var file ContextsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return KongContext{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(file.Contexts))
+	for _, kongContext := range file.Contexts {
+		if kongContext.Name == name {
+			return kongContext, nil
+		}
+		names = append(names, kongContext.Name)
+	}
+	return KongContext{}, fmt.Errorf("context %q not found in %s (available: %s)", name, path, strings.Join(names, ", "))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// EntityCoverage tells in how many workspaces an entity type is present,
+// that is has a count above zero, and which workspaces lack it.
+type EntityCoverage struct {
+	Field   string   `json:"field"`
+	Present int      `json:"present"`
+	Absent  []string `json:"absent"`
+}
+
+// maxAbsentNames caps the workspace names listed per row of the coverage
+// table; the JSON output always lists them all.
+const maxAbsentNames = 5
+
+// entityCoverage computes the coverage of every entity type across the
+// reported workspaces.
+func entityCoverage(report Report, excluded map[string]bool) []EntityCoverage {
+	columns := metadataColumns(report.Workspaces, excluded)
+	coverage := make([]EntityCoverage, 0, len(columns))
+	for _, column := range columns {
+		entry := EntityCoverage{Field: column, Absent: []string{}}
+		for _, metadata := range report.Workspaces {
+			if metadata.Meta.Counts[column] > 0 {
+				entry.Present++
+			} else {
+				entry.Absent = append(entry.Absent, metadata.WorkspaceName)
+			}
+		}
+		coverage = append(coverage, entry)
+	}
+	return coverage
+}
+
+// writeCoverage renders the coverage as a table that marks the entity types
+// used by only some workspaces, or as JSON when format is json.
+func writeCoverage(w io.Writer, format string, opts RenderOptions, coverage []EntityCoverage) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(coverage)
+	}
+
+	if !opts.Quiet {
+		fmt.Fprintln(w, "Entity Coverage:")
+	}
+	table := opts.newTable(w, []string{"Meta Field", "Present", "Absent", "Coverage", "Absent In"})
+	for _, entry := range coverage {
+		status := "all"
+		switch {
+		case entry.Present == 0:
+			status = "none"
+		case len(entry.Absent) > 0:
+			status = "partial"
+			if opts.Color {
+				status = colorize(status, "33")
+			}
+		}
+
+		absentIn := ""
+		if entry.Present > 0 {
+			names := entry.Absent
+			if len(names) > maxAbsentNames {
+				names = append(names[:maxAbsentNames:maxAbsentNames], fmt.Sprintf("+%d more", len(entry.Absent)-maxAbsentNames))
+			}
+			absentIn = strings.Join(names, ", ")
+		}
+
+		table.Append([]string{entry.Field, strconv.Itoa(entry.Present), strconv.Itoa(len(entry.Absent)), status, absentIn})
+	}
+	table.Render()
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// declarativeSkipFields are nested lists of objects that are not entities of
+// their own: plugin configuration, route sources and destinations, and the
+// consumer and consumer group references of consumer groups and consumers.
+var declarativeSkipFields = map[string]bool{
+	"config":       true,
+	"sources":      true,
+	"destinations": true,
+	"groups":       true,
+	"consumers":    true,
+}
+
+// loadDeclarativeMetadata counts the entities of a Kong declarative config
+// file, as used by DB-less Kong and decK. Entities nested under others, such
+// as routes under a service, are counted with their own type. The config is
+// reported as a single workspace named by its _workspace key, or default.
+func loadDeclarativeMetadata(path string) ([]WorkspaceMetadata, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so this reads both formats
+	var config map[string]any
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if config == nil {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+
+	workspace := "default"
+	if name, ok := config["_workspace"].(string); ok && name != "" {
+		workspace = name
+	}
+
+	counts := make(map[string]int)
+	for key, value := range config {
+		// Keys such as _format_version are settings, not entities
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		countDeclarativeEntities(key, value, counts)
+	}
+
+	return []WorkspaceMetadata{{WorkspaceName: workspace, Meta: Metadata{Counts: counts}}}, nil
+}
+
+// countDeclarativeEntities adds the objects of an entity list to counts and
+// recurses into the entity lists nested in each of them.
+func countDeclarativeEntities(entity string, value any, counts map[string]int) {
+	items, ok := value.([]any)
+	if !ok {
+		return
+	}
+
+	for _, item := range items {
+		object, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		counts[entity]++
+
+		for field, nested := range object {
+			if !declarativeSkipFields[field] {
+				countDeclarativeEntities(field, nested, counts)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// forEachLimit calls fn for every index below n using up to limit goroutines.
+// The first error cancels the context passed to the remaining calls and is
+// returned. Callers store results by index so goroutines never share a slot,
+// which keeps nested calls safe: a worker may itself call forEachLimit.
+func forEachLimit(ctx context.Context, n int, limit int, fn func(ctx context.Context, index int) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < limit && i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				if workCtx.Err() != nil {
+					continue
+				}
+				if err := fn(workCtx, index); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for index := 0; index < n; index++ {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
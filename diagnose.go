@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// diagnosticCheck is one line of the --diagnose checklist.
+type diagnosticCheck struct {
+	Name   string
+	Passed bool
+	Detail string
+	Hint   string
+}
+
+// runDiagnostics checks that the admin API is reachable, that the configured
+// credentials can list workspaces and that the meta endpoint is available.
+// Later checks are skipped once an earlier one fails.
+func runDiagnostics(ctx context.Context, client *Client, baseURL string) []diagnosticCheck {
+	checks := make([]diagnosticCheck, 0, 3)
+
+	// Reachability: any HTTP response from the root endpoint will do
+	resp, err := client.do(ctx, "GET", baseURL+"/")
+	if err != nil {
+		return append(checks, diagnosticCheck{
+			Name:   "Admin API reachable at " + baseURL,
+			Detail: err.Error(),
+			Hint:   "check --kong-addr or KONG_ADMIN_ADDR and that the admin listener is exposed to this host",
+		})
+	}
+	resp.Body.Close()
+	checks = append(checks, diagnosticCheck{
+		Name:   "Admin API reachable at " + baseURL,
+		Passed: true,
+		Detail: resp.Status,
+	})
+
+	// Authentication: listing workspaces must return 200
+	resp, err = client.do(ctx, "GET", baseURL+"/workspaces")
+	if err != nil {
+		return append(checks, diagnosticCheck{Name: "Workspaces can be listed", Detail: err.Error()})
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return append(checks, diagnosticCheck{Name: "Workspaces can be listed", Detail: err.Error()})
+	}
+	if resp.StatusCode != http.StatusOK {
+		return append(checks, diagnosticCheck{
+			Name:   "Workspaces can be listed",
+			Detail: resp.Status,
+			Hint:   statusHint(resp.StatusCode),
+		})
+	}
+
+	var workspaces []Workspace
+	if err := decodeList(body, client.DataField, &workspaces); err != nil {
+		return append(checks, diagnosticCheck{
+			Name:   "Workspaces can be listed",
+			Detail: err.Error(),
+			Hint:   "the response is not a Kong workspace listing; check the address points at the admin API, or set --data-field if a proxy renames the list field",
+		})
+	}
+	checks = append(checks, diagnosticCheck{
+		Name:   "Workspaces can be listed",
+		Passed: true,
+		Detail: fmt.Sprintf("%d workspaces", len(workspaces)),
+	})
+	if len(workspaces) == 0 {
+		return checks
+	}
+
+	// Meta endpoint: try the first listed workspace
+	sample := workspaces[0].Name
+	name := "Meta endpoint available for workspace " + sample
+	resp, err = client.do(ctx, "GET", baseURL+"/workspaces/"+sample+"/meta")
+	if err != nil {
+		return append(checks, diagnosticCheck{Name: name, Detail: err.Error()})
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return append(checks, diagnosticCheck{
+			Name:   name,
+			Detail: resp.Status,
+			Hint:   statusHint(resp.StatusCode),
+		})
+	}
+	return append(checks, diagnosticCheck{Name: name, Passed: true, Detail: resp.Status})
+}
+
+// statusHint suggests a remediation for a failed admin API status code.
+func statusHint(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "the admin API requires credentials; pass them with --token, --bearer or --basic-auth"
+	case http.StatusForbidden:
+		return "the credentials lack permission; check the RBAC roles of the admin token"
+	case http.StatusNotFound:
+		return "the endpoint does not exist; workspaces and /meta require Kong Enterprise"
+	default:
+		return "unexpected status from the admin API; check the Kong error log"
+	}
+}
+
+// printDiagnostics writes the checklist and reports whether every check passed.
+func printDiagnostics(w io.Writer, checks []diagnosticCheck) bool {
+	passed := true
+	for _, check := range checks {
+		status := "PASS"
+		if !check.Passed {
+			status = "FAIL"
+			passed = false
+		}
+
+		fmt.Fprintf(w, "[%s] %s", status, check.Name)
+		if check.Detail != "" {
+			fmt.Fprintf(w, " (%s)", check.Detail)
+		}
+		fmt.Fprintln(w)
+		if check.Hint != "" {
+			fmt.Fprintf(w, "       hint: %s\n", check.Hint)
+		}
+	}
+	return passed
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// CountChange is the difference in one entity count of one workspace between
+// two reports.
+type CountChange struct {
+	Workspace string `json:"workspace"`
+	Field     string `json:"field"`
+	Baseline  int    `json:"baseline"`
+	Current   int    `json:"current"`
+	Delta     int    `json:"delta"`
+	Status    string `json:"status,omitempty"`
+}
+
+// loadReport reads a report previously written with --output json, with or
+// without --key-by-id, from stdin when path is "-".
+func loadReport(path string) (Report, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return Report{}, err
+	}
+
+	// A report saved with --key-by-id holds the workspaces in an object
+	var shape struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := json.Unmarshal(data, &shape); err != nil {
+		return Report{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if workspaces := bytes.TrimSpace(shape.Workspaces); len(workspaces) > 0 && workspaces[0] == '{' {
+		var keyed KeyedReport
+		if err := json.Unmarshal(data, &keyed); err != nil {
+			return Report{}, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		return keyed.report(), nil
+	}
+
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return Report{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return report, nil
+}
+
+// diffReports returns every workspace entity count that differs between the
+// baseline and the current report, ordered by workspace and field. A
+// workspace or field missing from one side counts as zero there.
+func diffReports(baseline Report, current Report) []CountChange {
+	baselineCounts := comparedCounts(baseline)
+	currentCounts := comparedCounts(current)
+
+	names := make([]string, 0, len(baselineCounts)+len(currentCounts))
+	for name := range baselineCounts {
+		names = append(names, name)
+	}
+	for name := range currentCounts {
+		if _, ok := baselineCounts[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]CountChange, 0)
+	for _, name := range names {
+		fields := make(map[string]int)
+		updateCounts(baselineCounts[name], fields)
+		updateCounts(currentCounts[name], fields)
+
+		for _, field := range sortedKeys(fields) {
+			before := baselineCounts[name][field]
+			after := currentCounts[name][field]
+			if before != after {
+				changes = append(changes, CountChange{
+					Workspace: name,
+					Field:     field,
+					Baseline:  before,
+					Current:   after,
+					Delta:     after - before,
+				})
+			}
+		}
+	}
+	return changes
+}
+
+// diffSnapshots is diffReports between two saved reports, with each change
+// marked "added" or "removed" when its workspace or field exists on only one
+// side, and "changed" otherwise.
+func diffSnapshots(before Report, after Report) []CountChange {
+	beforeCounts := comparedCounts(before)
+	afterCounts := comparedCounts(after)
+
+	changes := diffReports(before, after)
+	for i, change := range changes {
+		_, inBefore := beforeCounts[change.Workspace][change.Field]
+		_, inAfter := afterCounts[change.Workspace][change.Field]
+		switch {
+		case !inBefore:
+			changes[i].Status = "added"
+		case !inAfter:
+			changes[i].Status = "removed"
+		default:
+			changes[i].Status = "changed"
+		}
+	}
+	return changes
+}
+
+// filterGrowth keeps the changes where a field grew by more than its
+// threshold. Fields without a threshold are dropped.
+func filterGrowth(changes []CountChange, thresholds map[string]int) []CountChange {
+	alerts := make([]CountChange, 0)
+	for _, change := range changes {
+		if threshold, ok := thresholds[change.Field]; ok && change.Delta > threshold {
+			alerts = append(alerts, change)
+		}
+	}
+	return alerts
+}
+
+// shrunkTotals returns the entity types whose cluster-wide total is lower
+// than in the baseline, the largest decrease first.
+func shrunkTotals(baseline Report, current Report) []CountChange {
+	shrunk := make([]CountChange, 0)
+	for _, field := range sortedKeys(baseline.Totals) {
+		if before, after := baseline.Totals[field], current.Totals[field]; after < before {
+			shrunk = append(shrunk, CountChange{Field: field, Baseline: before, Current: after, Delta: after - before})
+		}
+	}
+	sort.SliceStable(shrunk, func(i, j int) bool { return shrunk[i].Delta < shrunk[j].Delta })
+	return shrunk
+}
+
+// printShrunkTotals lists the entity types whose total shrank.
+func printShrunkTotals(w io.Writer, shrunk []CountChange) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Meta Field", "Baseline", "Current", "Delta"})
+	for _, change := range shrunk {
+		table.Append([]string{
+			change.Field,
+			strconv.Itoa(change.Baseline),
+			strconv.Itoa(change.Current),
+			fmt.Sprintf("%+d", change.Delta),
+		})
+	}
+	table.Render()
+}
+
+// sumCounts adds up every count.
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// workspaceCounts indexes the counts of a report by workspace name.
+func workspaceCounts(report Report) map[string]map[string]int {
+	counts := make(map[string]map[string]int, len(report.Workspaces))
+	for _, metadata := range report.Workspaces {
+		counts[metadata.WorkspaceName] = metadata.Meta.Counts
+	}
+	return counts
+}
+
+// comparedCounts is workspaceCounts including a separate default workspace,
+// so that changes to it are reported too.
+func comparedCounts(report Report) map[string]map[string]int {
+	counts := workspaceCounts(report)
+	if report.Default != nil {
+		counts[report.Default.WorkspaceName] = report.Default.Meta.Counts
+	}
+	return counts
+}
+
+// statusMarks prefix the status of a change the way diff marks lines.
+var statusMarks = map[string]string{"added": "+", "removed": "-", "changed": "~"}
+
+// writeDiff renders the changes as a table under title, or as JSON when
+// format is json. Without changes only the none message is printed. A Change
+// column is added when the changes are marked with a status.
+func writeDiff(w io.Writer, format string, quiet bool, title string, none string, changes []CountChange) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(changes)
+	}
+
+	if len(changes) == 0 {
+		if !quiet {
+			fmt.Fprintln(w, none)
+		}
+		return nil
+	}
+
+	if !quiet {
+		fmt.Fprintln(w, title+":")
+	}
+	marked := changes[0].Status != ""
+	header := []string{"Workspace Name", "Meta Field", "Baseline", "Current", "Delta"}
+	alignment := []int{
+		tablewriter.ALIGN_LEFT, tablewriter.ALIGN_LEFT,
+		tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT,
+	}
+	if marked {
+		header = append([]string{"Change"}, header...)
+		alignment = append([]int{tablewriter.ALIGN_LEFT}, alignment...)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetColumnAlignment(alignment)
+	for _, change := range changes {
+		row := []string{
+			change.Workspace,
+			change.Field,
+			strconv.Itoa(change.Baseline),
+			strconv.Itoa(change.Current),
+			fmt.Sprintf("%+d", change.Delta),
+		}
+		if marked {
+			row = append([]string{statusMarks[change.Status] + " " + change.Status}, row...)
+		}
+		table.Append(row)
+	}
+	table.Render()
+	return nil
+}
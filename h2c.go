@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	"golang.org/x/net/http2"
+)
+
+// h2cTransport speaks HTTP/2 over cleartext TCP with prior knowledge, for
+// admin endpoints that only serve h2c. It never uses TLS, so it must only be
+// used on trusted internal networks. Connections are opened with dial, or
+// directly when it is nil.
+func h2cTransport(dial func(ctx context.Context, network string, addr string) (net.Conn, error)) *http2.Transport {
+	if dial == nil {
+		var dialer net.Dialer
+		dial = dialer.DialContext
+	}
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network string, addr string, _ *tls.Config) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ClusterIdentity labels a report with the Kong node it was collected from.
+type ClusterIdentity struct {
+	Address  string `json:"address"`
+	Hostname string `json:"hostname"`
+	NodeID   string `json:"node_id"`
+	Version  string `json:"version"`
+}
+
+// getClusterIdentity reads the node's hostname, id and version from the
+// admin API root endpoint.
+func getClusterIdentity(ctx context.Context, client *Client, baseURL string) (*ClusterIdentity, error) {
+	body, err := client.get(ctx, baseURL+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &ClusterIdentity{Address: baseURL}
+	if err := json.Unmarshal(body, identity); err != nil {
+		return nil, err
+	}
+	identity.Address = baseURL
+	return identity, nil
+}
+
+// printClusterIdentity writes the one-line identity header of table output.
+func printClusterIdentity(w io.Writer, identity *ClusterIdentity) {
+	fmt.Fprintf(w, "Cluster: %s (node %s, Kong %s) at %s\n", identity.Hostname, identity.NodeID, identity.Version, identity.Address)
+}
+
+// oldestTestedKong and newestTestedKong bound the Kong major.minor versions
+// the tool was tested against. Other versions may report fields differently.
+var (
+	oldestTestedKong = [2]int{2, 0}
+	newestTestedKong = [2]int{3, 4}
+)
+
+// kongVersionWarning returns a warning when version, as reported by the
+// admin API root (e.g. "3.4.1.0-enterprise-edition"), is outside the tested
+// range, or "" when it is within it or cannot be parsed.
+func kongVersionWarning(version string) string {
+	var major, minor int
+	if _, err := fmt.Sscanf(version, "%d.%d", &major, &minor); err != nil {
+		return ""
+	}
+
+	switch {
+	case major < oldestTestedKong[0] || major == oldestTestedKong[0] && minor < oldestTestedKong[1]:
+		return fmt.Sprintf("Kong %s is older than %d.%d, the oldest version this tool was tested against; counts may be incomplete", version, oldestTestedKong[0], oldestTestedKong[1])
+	case major > newestTestedKong[0] || major == newestTestedKong[0] && minor > newestTestedKong[1]:
+		return fmt.Sprintf("Kong %s is newer than %d.%d, the newest version this tool was tested against; new fields may be missing or renamed", version, newestTestedKong[0], newestTestedKong[1])
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// influxEscaper escapes the characters that are significant in line
+// protocol measurement names, tag keys and tag values.
+var influxEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// writeInflux emits the counts in InfluxDB line protocol: one line per
+// workspace and entity in opts.InfluxMeasurement, and the totals per entity
+// in the same measurement suffixed with _total. opts.InfluxTags are added to
+// every line, merged with the entity and workspace tags and sorted by key as
+// InfluxDB recommends.
+func writeInflux(w io.Writer, opts RenderOptions, report Report) error {
+	measurement := influxEscaper.Replace(opts.InfluxMeasurement)
+	timestamp := time.Now().UnixNano()
+
+	workspaces := report.Workspaces
+	if report.Default != nil {
+		workspaces = append([]WorkspaceMetadata{*report.Default}, workspaces...)
+	}
+	for _, metadata := range workspaces {
+		for _, field := range sortedKeys(metadata.Meta.Counts) {
+			tags := influxTagSet(opts.InfluxTags, map[string]string{"entity": field, "workspace": metadata.WorkspaceName})
+			_, err := fmt.Fprintf(w, "%s%s value=%di %d\n", measurement, tags, metadata.Meta.Counts[field], timestamp)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, field := range sortedKeys(report.Totals) {
+		tags := influxTagSet(opts.InfluxTags, map[string]string{"entity": field})
+		_, err := fmt.Fprintf(w, "%s_total%s value=%di %d\n", measurement, tags, report.Totals[field], timestamp)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// influxTagSet formats the static and line tags as ",key=value" pairs sorted
+// by key, with a line tag replacing a static tag of the same key.
+func influxTagSet(static map[string]string, line map[string]string) string {
+	tags := make(map[string]string, len(static)+len(line))
+	for key, value := range static {
+		tags[key] = value
+	}
+	for key, value := range line {
+		tags[key] = value
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var set strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&set, ",%s=%s", influxEscaper.Replace(key), influxEscaper.Replace(tags[key]))
+	}
+	return set.String()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// PluginVersion is one line of the --plugins-inventory output: the plugin
+// instances sharing a name and installed version, with every protocol any of
+// them is configured for.
+type PluginVersion struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version"`
+	Protocols  []string `json:"protocols"`
+	Workspaces []string `json:"workspaces"`
+	Count      int      `json:"count"`
+}
+
+// unknownVersion is reported for plugins the node does not list a version
+// for, such as custom plugins on older Kong releases.
+const unknownVersion = "unknown"
+
+// getPluginVersions reads the installed version of every plugin from the
+// available_on_server map of the admin API root. Kong releases that only
+// report availability leave the versions unknown.
+func getPluginVersions(ctx context.Context, client *Client, baseURL string) (map[string]string, error) {
+	body, err := client.get(ctx, baseURL+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	var root struct {
+		Plugins struct {
+			AvailableOnServer map[string]json.RawMessage `json:"available_on_server"`
+		} `json:"plugins"`
+	}
+	if err := json.Unmarshal(body, &root); err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string, len(root.Plugins.AvailableOnServer))
+	for name, raw := range root.Plugins.AvailableOnServer {
+		var plugin struct {
+			Version string `json:"version"`
+		}
+		if json.Unmarshal(raw, &plugin) == nil && plugin.Version != "" {
+			versions[name] = plugin.Version
+		}
+	}
+	return versions, nil
+}
+
+// collectPluginInventory lists the plugins of every workspace and counts
+// them by name and installed version, collecting the union of their
+// configured protocols. Up to concurrency workspaces are listed in parallel.
+func collectPluginInventory(ctx context.Context, client *Client, baseURL string, workspaces []Workspace, concurrency int) ([]PluginVersion, error) {
+	versions, err := getPluginVersions(ctx, client, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("reading plugin versions: %w", err)
+	}
+	listings, err := listWorkspacePlugins(ctx, client, baseURL, workspaces, concurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := make(map[string]*PluginVersion)
+	for index, plugins := range listings {
+		workspace := workspaces[index]
+		for _, plugin := range plugins {
+			name := stringField(plugin, "name")
+			version, ok := versions[name]
+			if !ok {
+				version = unknownVersion
+			}
+			key := name + "\x00" + version
+			entry, ok := inventory[key]
+			if !ok {
+				entry = &PluginVersion{Name: name, Version: version, Protocols: []string{}, Workspaces: []string{}}
+				inventory[key] = entry
+			}
+			for _, protocol := range stringsField(plugin, "protocols") {
+				if !slices.Contains(entry.Protocols, protocol) {
+					entry.Protocols = append(entry.Protocols, protocol)
+				}
+			}
+			entry.Count++
+			if n := len(entry.Workspaces); n == 0 || entry.Workspaces[n-1] != workspace.Name {
+				entry.Workspaces = append(entry.Workspaces, workspace.Name)
+			}
+		}
+	}
+
+	result := make([]PluginVersion, 0, len(inventory))
+	for _, entry := range inventory {
+		sort.Strings(entry.Protocols)
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+// writePluginInventory renders the inventory as a table, or as JSON when
+// format is json.
+func writePluginInventory(w io.Writer, format string, quiet bool, inventory []PluginVersion) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(inventory)
+	}
+
+	if !quiet {
+		fmt.Fprintln(w, "Plugin Inventory:")
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Plugin", "Version", "Protocols", "Workspaces", "Count"})
+	table.SetAutoWrapText(false)
+	for _, entry := range inventory {
+		table.Append([]string{
+			entry.Name,
+			entry.Version,
+			strings.Join(entry.Protocols, ","),
+			strconv.Itoa(len(entry.Workspaces)),
+			strconv.Itoa(entry.Count),
+		})
+	}
+	table.Render()
+	return nil
+}
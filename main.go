@@ -1,27 +1,50 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
 
-	"github.com/olekukonko/tablewriter"
+	"go.opentelemetry.io/otel"
 )
 
 type Workspace struct {
 	Name string `json:"name"`
 	ID   string `json:"id"`
+	// CreatedAt and UpdatedAt are Unix timestamps in seconds, zero when the
+	// listing does not include them
+	CreatedAt int64 `json:"created_at"`
+	UpdatedAt int64 `json:"updated_at"`
 	// Add more fields as needed
 }
 
-type WorkspaceResponse struct {
-	Data []Workspace `json:"data"`
+// modifiedAt returns when the workspace last changed, falling back to its
+// creation time when the listing has no updated_at.
+func (w Workspace) modifiedAt() time.Time {
+	if w.UpdatedAt > 0 {
+		return time.Unix(w.UpdatedAt, 0)
+	}
+	return time.Unix(w.CreatedAt, 0)
 }
 
 type Metadata struct {
@@ -29,126 +52,1515 @@ type Metadata struct {
 	// Add more fields as needed
 }
 
+// UnmarshalJSON accepts counts encoded with a decimal point (12.0), which
+// some Kong builds return, and rounds them to integers.
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Counts map[string]float64 `json:"counts"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	m.Counts = make(map[string]int, len(raw.Counts))
+	for field, count := range raw.Counts {
+		m.Counts[field] = int(math.Round(count))
+	}
+	return nil
+}
+
 type WorkspaceMetadata struct {
-	WorkspaceName string
-	Meta          Metadata
+	WorkspaceName string   `json:"workspace"`
+	Meta          Metadata `json:"meta"`
+	// Raw is the unparsed /meta response body, only collected with --raw
+	Raw []byte `json:"-"`
+	// ID is the workspace id from the listing, only output with --key-by-id
+	ID string `json:"-"`
 }
 
+// Report is the collected data handed to the output writers.
+type Report struct {
+	Workspaces []WorkspaceMetadata `json:"workspaces"`
+	Totals     map[string]int      `json:"totals"`
+	// Default holds the default workspace when it is reported separately
+	// from the team-owned workspaces.
+	Default *WorkspaceMetadata `json:"default,omitempty"`
+	// Cluster identifies the Kong node the report came from, if requested
+	Cluster *ClusterIdentity `json:"cluster,omitempty"`
+}
+
+// stringSliceFlag is a flag.Value that collects every occurrence of a
+// repeatable flag.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// exitDiff is the exit status of a --baseline comparison that found
+// differences with --fail-on-diff, growth over an --alert-delta or a total
+// below --min-total-ratio. It is
+// kept apart from 1 so pipelines can tell a mismatch from an error.
+const exitDiff = 3
+
+// adaptiveMaxConcurrency is the ceiling of --adaptive when --concurrency is
+// left at its default.
+const adaptiveMaxConcurrency = 16
+
 func main() {
+	os.Exit(run())
+}
+
+// run executes the tool and returns the process exit code. Deferred cleanup
+// such as flushing traces runs before the process exits.
+func run() (code int) {
 	// Parse command-line flags
-	urlPtr := flag.String("kong-addr", "", "workspace URL (e.g. http://localhost:8001)")
-	headersPtr := flag.String("headers", "", "headers to include in the HTTP request")
+	var kongAddrs stringSliceFlag
+	flag.Var(&kongAddrs, "kong-addr", "workspace URL (e.g. http://localhost:8001); repeat to scan several clusters concurrently")
+	basePathPtr := flag.String("base-path", "", "path prefix of the admin API behind path-based routing (e.g. /kong-admin/)")
+	clusterConcurrencyPtr := flag.Int("cluster-concurrency", 4, "number of clusters scanned in parallel when --kong-addr is repeated")
+	contextPtr := flag.String("context", "", "named context (admin address and token) to use from the contexts file")
+	configPtr := flag.String("config", "", "YAML file with the admin address, token and timeout, optionally as named profiles")
+	profilePtr := flag.String("profile", "", "named profile to use from the --config file")
+	contextsFilePtr := flag.String("contexts-file", defaultContextsFile(), "contexts file read by --context (env: KONG_CONTEXTS)")
 	metaPtr := flag.String("meta", "counts", "metadata option: 'workspace', or 'all'")
+	identityPtr := flag.Bool("identity", false, "label the report with the node hostname, id and Kong version read from the admin API root")
+	anonymizePtr := flag.Bool("anonymize", false, "replace workspace names with sequential labels (e.g. ws-001)")
+	anonymizeMapPtr := flag.String("anonymize-map", "", "file to write the real-to-anonymized workspace name mapping to (JSON)")
+	outputPtr := flag.String("output", envOrDefault("KONG_WS_OUTPUT", "table"), "output format: 'table', 'table-wide' (aligned columns without borders), 'json', 'tree-json', 'grafana-json', 'influx', 'prometheus', 'openmetrics', 'line', 'ndjson', 'env' or 'nested-text' (env: KONG_WS_OUTPUT)")
+	formatTemplatePtr := flag.String("format-template", "", "Go text/template rendered against the collected data instead of --output (helpers: field, sum, keys)")
+	influxMeasurementPtr := flag.String("influx-measurement", "kong_workspace", "measurement name for --output influx; totals use the name with a _total suffix")
+	var influxTags stringSliceFlag
+	flag.Var(&influxTags, "tag", "key=value tag added to every --output influx line (repeatable)")
+	outFilePtr := flag.String("out-file", "", "write output to this file instead of stdout")
+	keyByIDPtr := flag.Bool("key-by-id", false, "key the workspaces of --output json by workspace id instead of listing them, which keeps joins stable across renames")
+	prettyPtr := flag.Bool("pretty", false, "highlight keys and numbers of JSON output when stdout is a terminal")
+	clipboardPtr := flag.Bool("clipboard", false, "also copy the printed output to the system clipboard")
+	gzipPtr := flag.Bool("gzip", false, "gzip the --out-file, adding a .gz suffix to its name")
+	servePtr := flag.String("serve", "", "listen address (e.g. :9100) to serve Prometheus metrics at /metrics, collecting on each scrape")
+	serveCachePtr := flag.Duration("serve-cache", 15*time.Second, "with --serve, reuse the last collection for scrapes within this interval")
+	teePtr := flag.Bool("tee", false, "print the table to stdout and write a JSON copy to --out-file")
+	maxRedirectsPtr := flag.Int("max-redirects", 10, "maximum number of redirects to follow, keeping auth headers across hosts")
+	coveragePtr := flag.Bool("coverage", false, "show in how many workspaces each entity type is present, marking the ones only some workspaces use")
+	checksumPtr := flag.Bool("checksum", false, "print only a SHA-256 of the collected counts, which stays the same while nothing changes")
+	baselinePtr := flag.String("baseline", "", "JSON output of a previous run to diff the current counts against")
+	var alertDelta stringSliceFlag
+	flag.Var(&alertDelta, "alert-delta", "entity=N: with --baseline, only report workspaces where the entity grew by more than N, exiting with status 3 if any did (repeatable)")
+	minTotalRatioPtr := flag.Float64("min-total-ratio", 0, "with --baseline, exit with status 3 if the total entity count falls below this fraction of the baseline total (e.g. 0.9)")
+	failOnDiffPtr := flag.Bool("fail-on-diff", false, "with --baseline or --diff, exit with status 3 when any count differs")
+	byTagPtr := flag.Bool("by-tag", false, "count entities per tag value across workspaces instead of per workspace")
+	pluginsByScopePtr := flag.Bool("plugins-by-scope", false, "list plugins in every workspace and count them by scope: global, service, route or consumer")
+	pluginsInventoryPtr := flag.Bool("plugins-inventory", false, "list plugins in every workspace and count them by name and installed version, listing the protocols they are configured for")
+	pluginsDetailPtr := flag.Bool("plugins-detail", false, "list plugins in every workspace and count them by plugin name")
+	enabledOnlyPtr := flag.Bool("enabled-only", false, "also count only enabled plugins in the plugin breakdown; implies --plugins-detail")
+	diffPtr := flag.Bool("diff", false, "compare two reports saved with --output json, given as the arguments FILE_A FILE_B, without querying the admin API")
+	fromJSONPtr := flag.String("from-json", "", "render a report saved with --output json from this file ('-' for stdin) instead of querying the admin API")
+	declarativeFilePtr := flag.String("declarative-file", "", "count entities in a Kong declarative config (YAML or JSON) instead of querying the admin API, e.g. for DB-less Kong")
+	checkAccessPtr := flag.Bool("check-access", false, "probe the meta endpoint of every workspace and report which ones the credentials can read, then exit (status 1 if any cannot)")
+	diagnosePtr := flag.Bool("diagnose", false, "check connectivity, credentials and the meta endpoint, then exit")
+	disableKeepalivePtr := flag.Bool("disable-keepalive", false, "use a new connection for every request, working around proxies that mix up responses on reused connections")
+	h2cPtr := flag.Bool("h2c", false, "use HTTP/2 over cleartext (h2c) to the admin API; only for trusted internal networks, as it skips TLS")
+	sshJumpPtr := flag.String("ssh-jump", "", "reach the admin API through an SSH bastion, as user@host[:port], authenticating with the SSH agent or ~/.ssh keys")
+	rawPtr := flag.Bool("raw", false, "output the untouched /meta response of each workspace as a JSON object keyed by workspace")
+	tuiPtr := flag.Bool("tui", false, "browse the collected workspaces in an interactive terminal UI")
+	verbosePtr := flag.Bool("verbose", false, "log each admin API request and its response status to stderr")
+	dumpHeadersPtr := flag.Bool("dump-headers", false, "also log request headers (credentials redacted) and response headers; implies --verbose")
+	otelPtr := flag.Bool("otel", false, "export OpenTelemetry traces of the run via OTLP (configured from OTEL_* env vars)")
+	humanPtr := flag.Bool("human", false, "format table counts with thousands separators (e.g. 1,234,567)")
+	humanSIPtr := flag.Bool("human-si", false, "format table counts with SI suffixes (e.g. 1.2M)")
+	sortWorkspacesPtr := flag.String("sort-workspaces", "name", "per-workspace row order: 'name' (numeric-aware), 'lexical' or 'none' (as listed by Kong)")
+	proportionPtr := flag.Bool("proportion", false, "show a stacked bar of each field's share of the total below the counts table")
+	noHeadersPtr := flag.Bool("no-headers", false, "omit the header row from tables")
+	noBordersPtr := flag.Bool("no-borders", false, "omit the box-drawing borders from tables")
+	noColorPtr := flag.Bool("no-color", false, "disable colored output (also disabled by NO_COLOR or when stdout is not a terminal)")
+	transposePtr := flag.Bool("transpose", false, "show entity types as rows and workspaces as columns in the per-workspace table")
+	widePtr := flag.Bool("wide", false, "always print the full per-workspace table, even when wider than the terminal")
+	minCountPtr := flag.Int("min-count", 0, "hide fields whose total count is below N from the counts table")
+	printConfigPtr := flag.Bool("print-config", false, "print the resolved settings and where they came from to stderr, then run")
+	quietPtr := flag.Bool("quiet", false, "suppress banner lines and progress output, leaving only results and errors")
+	shufflePtr := flag.Bool("shuffle", false, "process workspaces in random order, spreading the load when several scanners run against one cluster")
+	seedPtr := flag.Int64("seed", 0, "seed for --shuffle, to reproduce an order (0 picks a random seed)")
+	benchPtr := flag.Bool("bench", false, "collect all metadata but print only the time taken and the workspaces and requests per second")
+	concurrencyPtr := flag.Int("concurrency", 1, "number of workspaces to fetch metadata for in parallel")
+	detailConcurrencyPtr := flag.Int("detail-concurrency", 1, "number of detail requests, such as the entity listings of --by-tag or the counts of --count-fallback, to run in parallel within each workspace")
+	adaptivePtr := flag.Bool("adaptive", false, "start with one request in flight and grow up to --concurrency (16 if unset) while the admin API stays healthy, backing off on 429, 503 or rising latency")
+	timingsFilePtr := flag.String("timings-file", "", "write the URL, workspace, duration and status of every admin API request to this file as a JSON array")
+	appendFilePtr := flag.String("append-file", "", "also append the result with a timestamp as one JSON line to this file, building a history")
+	xlsxPtr := flag.String("xlsx", "", "also write the counts to this Excel file, with a Workspaces and a Totals sheet")
+	splitDirPtr := flag.String("split-dir", "", "also write one JSON file per workspace into this directory")
+	excludeDefaultPtr := flag.Bool("exclude-default", false, "leave the default workspace out of the output and totals")
+	defaultSeparatePtr := flag.Bool("default-separate", false, "report the default workspace in its own section, outside the totals")
+	sparklinePtr := flag.Int("sparkline", 0, "with --watch, show a sparkline of the cluster-wide total over this many recent cycles above the tables")
+	changeLogPtr := flag.String("change-log", "", "with --watch, append the time and every count change of each cycle to this file")
+	watchPtr := flag.Duration("watch", 0, "re-collect and redraw the report at this interval, showing each count's change since the previous cycle (e.g. 30s)")
+	sincePtr := flag.Duration("since", 0, "only include workspaces created or updated within this duration (e.g. 72h)")
+	webhookPtr := flag.String("webhook", "", "POST the JSON result to this URL after collection")
+	statsdPtr := flag.String("statsd", "", "host:port of a StatsD agent to send every count to as a gauge over UDP")
+	statsdPrefixPtr := flag.String("statsd-prefix", "kong.workspace", "metric name prefix for --statsd")
+	var statsdTags stringSliceFlag
+	flag.Var(&statsdTags, "statsd-tag", "key:value tag added to every --statsd gauge (repeatable)")
+	var webhookHeaders stringSliceFlag
+	flag.Var(&webhookHeaders, "webhook-header", "'Name: value' header to send with the webhook request (repeatable)")
+	perRequestTimeoutPtr := flag.Duration("per-request-timeout", 0, "timeout for each request, listing and metadata alike (0 means no limit)")
+	listTimeoutPtr := flag.Duration("list-timeout", 0, "timeout for the workspace listing, overriding --per-request-timeout")
+	metaTimeoutPtr := flag.Duration("meta-timeout", 0, "timeout for each metadata request, overriding --per-request-timeout")
+	deadlinePtr := flag.Duration("deadline", 0, "overall time budget for collection; workspaces not reached by then are reported (0 means no limit)")
+	countFallbackPtr := flag.Bool("count-fallback", false, "for workspaces whose meta endpoint returns 404, read each entity list's reported total with size=0 instead")
+	ignoreMissingMetaPtr := flag.Bool("ignore-missing-meta", false, "skip workspaces whose meta endpoint returns 404, only counting them, instead of listing them as errors")
+	requirePtr := flag.String("require", "", "comma-separated workspace names that must exist")
+	excludeColumnsPtr := flag.String("exclude-columns", "", "comma-separated entity types to leave out of the tables and totals (e.g. snis,certificates)")
+	hideEmptyPtr := flag.Bool("hide-empty", false, "leave out workspaces whose counts are all zero")
+	emptyFieldsPtr := flag.String("empty-fields", "", "comma-separated entity types that decide emptiness for --hide-empty (e.g. services,routes); implies --hide-empty")
+	mergeGroupPtr := flag.String("merge-group", "", "regular expression; workspaces whose name matches are combined into one entry with summed counts")
+	mergeLabelPtr := flag.String("merge-label", "merged", "name of the entry combining the --merge-group workspaces")
+	var over stringSliceFlag
+	flag.Var(&over, "over", "entity=N: only report workspaces with more than N of the entity (repeatable, a workspace over any threshold is kept)")
+	weightsPtr := flag.String("weights", "", "comma-separated entity=weight pairs (e.g. services=1,routes=0.5) for a weighted score per workspace and in total")
+	topFieldsPtr := flag.Int("top-fields", 0, "only show the N highest-count fields in the counts table (0 shows all)")
+	var headers stringSliceFlag
+	flag.Var(&headers, "headers", "'Name: value' header to include in every HTTP request (repeatable)")
+	tokenPtr := flag.String("token", "", "Kong-Admin-Token to send with every request (env: KONG_ADMIN_TOKEN)")
+	tokenCommandPtr := flag.String("token-command", "", "shell command whose output is used as the Kong-Admin-Token, re-run on every --watch cycle")
+	bearerPtr := flag.String("bearer", "", "bearer token sent as 'Authorization: Bearer <token>'")
+	basicAuthPtr := flag.String("basic-auth", "", "'user:password' sent as HTTP basic auth, e.g. for a proxy in front of Kong")
+	maxPagesPtr := flag.Int("max-pages", 100, "stop listing workspaces after this many pages of 1000, warning that the list may be incomplete (0 means no limit)")
+	maxRequestsPtr := flag.Int64("max-requests", 0, "stop after this many admin API requests in total, listing included (0 means no limit)")
+	retriesPtr := flag.Int("retries", 2, "times to retry a request answered with a 5xx or --retry-status code, with exponential backoff")
+	retryBudgetPtr := flag.Int64("retry-budget", 0, "maximum number of retries across the whole run, after which failures are not retried (0 means no limit)")
+	retryStatusPtr := flag.String("retry-status", "", "comma-separated extra status codes to retry (e.g. 429); Retry-After is honored when present")
+	acceptPtr := flag.String("accept", "application/json", "Accept header sent with every admin API request; an Accept in --headers takes precedence")
+	dataFieldPtr := flag.String("data-field", "data", "top-level field holding the array in list responses, for proxies that rename Kong's 'data'")
+	var metaQuery stringSliceFlag
+	flag.Var(&metaQuery, "meta-query", "key=value query parameter appended to each metadata URL (repeatable)")
 	flag.Parse()
 
+	// A single-cluster run talks to the first --kong-addr
+	urlPtr := new(string)
+	if len(kongAddrs) > 0 {
+		*urlPtr = kongAddrs[0]
+	}
+	multiCluster := len(kongAddrs) > 1
+
+	// Read the settings of the selected profile, used where no flag is given
+	var profile Profile
+	configSource := "config"
+	if *profilePtr != "" {
+		configSource = "config profile " + *profilePtr
+		if *configPtr == "" {
+			fmt.Fprintln(os.Stderr, "Error: --profile requires --config")
+			return 2
+		}
+	}
+	if *configPtr != "" {
+		var err error
+		profile, err = loadProfile(*configPtr, *profilePtr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading config:", err)
+			return 2
+		}
+	}
+	timeoutSource := ""
+	if *perRequestTimeoutPtr == 0 && profile.Timeout != 0 {
+		*perRequestTimeoutPtr, timeoutSource = profile.Timeout, configSource
+	}
+
+	if *concurrencyPtr < 1 {
+		*concurrencyPtr = 1
+	}
+
+	// Without an explicit --concurrency, --adaptive may grow up to its ceiling
+	concurrencySet := false
+	flag.Visit(func(f *flag.Flag) { concurrencySet = concurrencySet || f.Name == "concurrency" })
+	if *adaptivePtr && !concurrencySet {
+		*concurrencyPtr = adaptiveMaxConcurrency
+	}
+
+	// Each phase falls back to the general per-request timeout
+	if *listTimeoutPtr == 0 {
+		*listTimeoutPtr = *perRequestTimeoutPtr
+	}
+	if *metaTimeoutPtr == 0 {
+		*metaTimeoutPtr = *perRequestTimeoutPtr
+	}
+
+	// Reject an unknown output format before any request is sent
+	if err := checkOutputFormat(*outputPtr); err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing --output:", err)
+		return 2
+	}
+
+	// Reject an unknown row order before any request is sent
+	if err := sortWorkspaceMetadata(nil, *sortWorkspacesPtr); err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing --sort-workspaces:", err)
+		return 2
+	}
+
+	if *teePtr && *outFilePtr == "" {
+		fmt.Fprintln(os.Stderr, "Error: --tee requires --out-file")
+		return 2
+	}
+	if *clipboardPtr && *outFilePtr != "" && !*teePtr {
+		fmt.Fprintln(os.Stderr, "Error: --clipboard copies what is printed, so it cannot be combined with --out-file unless --tee is given")
+		return 2
+	}
+
+	if *disableKeepalivePtr && *h2cPtr {
+		fmt.Fprintln(os.Stderr, "Error: --disable-keepalive cannot be combined with --h2c, which multiplexes requests over one connection")
+		return 2
+	}
+
+	// Report a closed pipe as a write error instead of dying of SIGPIPE, so
+	// streaming stops cleanly when the consumer goes away
+	if *outputPtr == "ndjson" {
+		signal.Ignore(syscall.SIGPIPE)
+	}
+
+	// A fixed seed makes the --shuffle order reproducible
+	shuffleSeed := *seedPtr
+	if shuffleSeed == 0 {
+		shuffleSeed = time.Now().UnixNano()
+	}
+
+	if *gzipPtr && *outFilePtr == "" {
+		fmt.Fprintln(os.Stderr, "Error: --gzip requires --out-file")
+		return 2
+	}
+
+	if *emptyFieldsPtr != "" {
+		*hideEmptyPtr = true
+	}
+
+	if *enabledOnlyPtr {
+		*pluginsDetailPtr = true
+	}
+
+	if *declarativeFilePtr != "" && (*watchPtr > 0 || *rawPtr || *byTagPtr || *pluginsDetailPtr || *pluginsByScopePtr || *pluginsInventoryPtr || *checkAccessPtr || *diagnosePtr) {
+		fmt.Fprintln(os.Stderr, "Error: --declarative-file cannot be combined with --watch, --raw, --by-tag, --plugins-detail, --plugins-by-scope, --plugins-inventory, --check-access or --diagnose")
+		return 2
+	}
+
+	if *fromJSONPtr != "" && (*declarativeFilePtr != "" || *watchPtr > 0 || *rawPtr || *byTagPtr || *pluginsDetailPtr || *pluginsByScopePtr || *pluginsInventoryPtr || *checkAccessPtr || *diagnosePtr) {
+		fmt.Fprintln(os.Stderr, "Error: --from-json cannot be combined with --declarative-file, --watch, --raw, --by-tag, --plugins-detail, --plugins-by-scope, --plugins-inventory, --check-access or --diagnose")
+		return 2
+	}
+
+	if multiCluster && (*watchPtr > 0 || *rawPtr || *tuiPtr || *byTagPtr || *pluginsDetailPtr || *pluginsByScopePtr || *pluginsInventoryPtr || *checkAccessPtr || *diagnosePtr || *declarativeFilePtr != "" || *fromJSONPtr != "" ||
+		*baselinePtr != "" || *formatTemplatePtr != "" || *checksumPtr || *coveragePtr || *benchPtr || *teePtr || *splitDirPtr != "" || *xlsxPtr != "" || *appendFilePtr != "" || *webhookPtr != "" || *statsdPtr != "" || *anonymizeMapPtr != "") {
+		fmt.Fprintln(os.Stderr, "Error: a repeated --kong-addr cannot be combined with --watch, --raw, --tui, --by-tag, --plugins-detail, --plugins-by-scope, --plugins-inventory, --check-access, --diagnose, --declarative-file, --from-json, --baseline, --format-template, --checksum, --coverage, --bench, --tee, --split-dir, --xlsx, --append-file, --webhook, --statsd or --anonymize-map")
+		return 2
+	}
+	if multiCluster && *outputPtr != "table" && *outputPtr != "table-wide" && *outputPtr != "nested-text" && *outputPtr != "json" {
+		fmt.Fprintln(os.Stderr, "Error: a repeated --kong-addr only supports --output table, table-wide, nested-text or json")
+		return 2
+	}
+	if *clusterConcurrencyPtr < 1 {
+		*clusterConcurrencyPtr = 1
+	}
+
+	if *servePtr != "" && (multiCluster || *watchPtr > 0 || *rawPtr || *tuiPtr || *byTagPtr || *pluginsDetailPtr || *pluginsByScopePtr || *pluginsInventoryPtr || *checkAccessPtr || *diagnosePtr || *declarativeFilePtr != "" || *fromJSONPtr != "" || *baselinePtr != "" ||
+		*outFilePtr != "" || *teePtr || *gzipPtr || *clipboardPtr || *splitDirPtr != "" || *xlsxPtr != "" || *appendFilePtr != "" || *webhookPtr != "" || *statsdPtr != "") {
+		fmt.Fprintln(os.Stderr, "Error: --serve cannot be combined with a repeated --kong-addr, --watch, --raw, --tui, --by-tag, --plugins-detail, --plugins-by-scope, --plugins-inventory, --check-access, --diagnose, --declarative-file, --from-json, --baseline, --out-file, --tee, --gzip, --clipboard, --split-dir, --xlsx, --append-file, --webhook or --statsd")
+		return 2
+	}
+
+	if *diffPtr && (multiCluster || *watchPtr > 0 || *servePtr != "" || *declarativeFilePtr != "" || *fromJSONPtr != "" || *baselinePtr != "") {
+		fmt.Fprintln(os.Stderr, "Error: --diff cannot be combined with a repeated --kong-addr, --watch, --serve, --declarative-file, --from-json or --baseline")
+		return 2
+	}
+
+	if *anonymizePtr && (*rawPtr || *pluginsDetailPtr || *pluginsByScopePtr || *pluginsInventoryPtr || *checkAccessPtr) {
+		fmt.Fprintln(os.Stderr, "Error: --anonymize cannot be combined with --raw, --plugins-detail, --plugins-by-scope, --plugins-inventory or --check-access, which report real workspace names")
+		return 2
+	}
+
+	if *failOnDiffPtr && *baselinePtr == "" && !*diffPtr {
+		fmt.Fprintln(os.Stderr, "Error: --fail-on-diff requires --baseline or --diff")
+		return 2
+	}
+	if *minTotalRatioPtr > 0 && *baselinePtr == "" {
+		fmt.Fprintln(os.Stderr, "Error: --min-total-ratio requires --baseline")
+		return 2
+	}
+	if len(alertDelta) > 0 && *baselinePtr == "" {
+		fmt.Fprintln(os.Stderr, "Error: --alert-delta requires --baseline")
+		return 2
+	}
+
+	if *sparklinePtr > 0 && *watchPtr == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --sparkline requires --watch")
+		return 2
+	}
+	if *changeLogPtr != "" && *watchPtr == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --change-log requires --watch")
+		return 2
+	}
+	if *watchPtr > 0 && (*rawPtr || *tuiPtr || *byTagPtr || *pluginsDetailPtr || *pluginsByScopePtr || *pluginsInventoryPtr || *checkAccessPtr || *baselinePtr != "" || *formatTemplatePtr != "" || *checksumPtr || *coveragePtr || *benchPtr ||
+		*outFilePtr != "" || *teePtr || *gzipPtr || *clipboardPtr || *splitDirPtr != "" || *xlsxPtr != "" || *appendFilePtr != "" || *webhookPtr != "" || *statsdPtr != "") {
+		fmt.Fprintln(os.Stderr, "Error: --watch cannot be combined with --raw, --tui, --by-tag, --plugins-detail, --plugins-by-scope, --plugins-inventory, --check-access, --baseline, --format-template, --checksum, --coverage, --bench, --out-file, --tee, --gzip, --clipboard, --split-dir, --xlsx, --append-file, --webhook or --statsd")
+		return 2
+	}
+
+	// Compare two saved reports offline if specified
+	if *diffPtr {
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "Error: --diff requires two files, e.g. --diff before.json after.json")
+			return 2
+		}
+		before, err := loadReport(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading snapshot:", err)
+			return 1
+		}
+		after, err := loadReport(flag.Arg(1))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading snapshot:", err)
+			return 1
+		}
+		changes := diffSnapshots(before, after)
+		title := fmt.Sprintf("Changes From %s To %s", flag.Arg(0), flag.Arg(1))
+		if err := writeDiff(os.Stdout, *outputPtr, *quietPtr, title, "No changes between the snapshots.", changes); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing output:", err)
+			return 1
+		}
+		if *failOnDiffPtr && len(changes) > 0 {
+			return exitDiff
+		}
+		return 0
+	}
+
+	// Load the baseline up front so a bad path fails before the scan
+	var baseline Report
+	if *baselinePtr != "" {
+		var err error
+		if baseline, err = loadReport(*baselinePtr); err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading baseline:", err)
+			return 1
+		}
+	}
+
+	// Remember where the address and token came from for --print-config
+	addrSource, tokenSource := "flag", "flag"
+
+	// Ask the credential helper for the token if specified
+	if *tokenCommandPtr != "" {
+		if *tokenPtr != "" {
+			fmt.Fprintln(os.Stderr, "Error: --token and --token-command cannot be combined")
+			return 2
+		}
+		token, err := runTokenCommand(context.Background(), *tokenCommandPtr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error running --token-command:", err)
+			return 1
+		}
+		*tokenPtr, tokenSource = token, "token-command"
+	}
+
+	// Use the address and token of the selected context, unless overridden
+	if *contextPtr != "" {
+		kongContext, err := loadContext(*contextsFilePtr, *contextPtr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading context:", err)
+			return 2
+		}
+		if *urlPtr == "" {
+			*urlPtr, addrSource = kongContext.KongAddr, "context "+*contextPtr
+		}
+		if *tokenPtr == "" {
+			*tokenPtr, tokenSource = kongContext.Token, "context "+*contextPtr
+		}
+	}
+
+	// Use the address and token of the config file next
+	if *urlPtr == "" && profile.KongAddr != "" {
+		*urlPtr, addrSource = profile.KongAddr, configSource
+	}
+	if *tokenPtr == "" && profile.Token != "" {
+		*tokenPtr, tokenSource = profile.Token, configSource
+	}
+
+	// Fallback to the token from the environment
+	if *tokenPtr == "" {
+		*tokenPtr, tokenSource = os.Getenv("KONG_ADMIN_TOKEN"), "env KONG_ADMIN_TOKEN"
+		if *tokenPtr == "" {
+			tokenSource = "default"
+		}
+	}
+
 	// Fallback to default URL if URL is empty
 	if *urlPtr == "" {
-		*urlPtr = os.Getenv("KONG_ADMIN_ADDR")
+		*urlPtr, addrSource = os.Getenv("KONG_ADMIN_ADDR"), "env KONG_ADMIN_ADDR"
 		if *urlPtr == "" {
-			*urlPtr = "http://localhost:8001"
+			*urlPtr, addrSource = "http://localhost:8001", "default"
 		}
 	}
 
-	// Send GET request to fetch workspaces
-	workspacesURL := *urlPtr + "/workspaces"
-	workspaces, err := getWorkspaces(workspacesURL)
+	// Put every admin call under the path prefix if specified
+	if *basePathPtr != "" {
+		var err error
+		if *urlPtr, err = withBasePath(*urlPtr, *basePathPtr); err != nil {
+			fmt.Fprintln(os.Stderr, "Error applying --base-path:", err)
+			return 2
+		}
+		for i := range kongAddrs {
+			if kongAddrs[i], err = withBasePath(kongAddrs[i], *basePathPtr); err != nil {
+				fmt.Fprintln(os.Stderr, "Error applying --base-path:", err)
+				return 2
+			}
+		}
+	}
+
+	// Compile the custom output template up front so mistakes fail fast
+	var formatTemplate *template.Template
+	if *formatTemplatePtr != "" {
+		var err error
+		formatTemplate, err = parseFormatTemplate(*formatTemplatePtr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing --format-template:", err)
+			return 2
+		}
+	}
+
+	webhookHeaderValues, err := parseHeaders(webhookHeaders)
 	if err != nil {
-		fmt.Println("Error getting workspaces:", err)
-		return
+		fmt.Fprintln(os.Stderr, "Error parsing --webhook-header:", err)
+		return 2
 	}
 
-	// Initialize counts
-	counts := make(map[string]int)
+	excludedColumns := parseColumnList(*excludeColumnsPtr)
+	emptyFields := parseColumnList(*emptyFieldsPtr)
 
-	// Iterate over workspaces and fetch metadata
-	workspaceMetadataList := make([]WorkspaceMetadata, 0)
+	var mergeGroup *regexp.Regexp
+	if *mergeGroupPtr != "" {
+		var err error
+		mergeGroup, err = regexp.Compile(*mergeGroupPtr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing --merge-group:", err)
+			return 2
+		}
+	}
 
-	for _, workspace := range workspaces {
-		metaURL := *urlPtr + "/workspaces/" + workspace.Name + "/meta"
-		meta, err := getMetadata(metaURL, *headersPtr)
+	thresholds, err := parseThresholds(over)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing --over:", err)
+		return 2
+	}
+
+	alertDeltas, err := parseThresholds(alertDelta)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing --alert-delta:", err)
+		return 2
+	}
+
+	weights, err := parseWeights(*weightsPtr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing --weights:", err)
+		return 2
+	}
+
+	// Build the static tags of the influx output
+	influxTagValues, err := parseQueryValues(influxTags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing --tag:", err)
+		return 2
+	}
+	influxTagMap := make(map[string]string, len(influxTagValues))
+	for key := range influxTagValues {
+		influxTagMap[key] = influxTagValues.Get(key)
+	}
+
+	// Build the query parameters appended to each metadata URL
+	metaValues, err := parseQueryValues(metaQuery)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing --meta-query:", err)
+		return 2
+	}
+
+	// Build the client shared by every admin API request
+	auth := AuthOptions{
+		BasicAuth: *basicAuthPtr,
+		Bearer:    *bearerPtr,
+		Token:     *tokenPtr,
+	}
+	client, err := newClient(headers, auth, *maxRedirectsPtr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error configuring authentication:", err)
+		return 2
+	}
+
+	client.DataField = *dataFieldPtr
+
+	// Negotiate JSON unless another representation is requested
+	if client.Headers.Get("Accept") == "" && *acceptPtr != "" {
+		client.Headers.Set("Accept", *acceptPtr)
+	}
+	client.MaxRequests = *maxRequestsPtr
+	client.Retries = *retriesPtr
+	client.RetryBudget = *retryBudgetPtr
+	client.RetryStatus, err = parseRetryStatus(*retryStatusPtr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing --retry-status:", err)
+		return 2
+	}
+
+	// Reach the admin API through an SSH bastion if specified
+	var transport http.RoundTripper = http.DefaultTransport
+	var dial func(ctx context.Context, network string, addr string) (net.Conn, error)
+	if *sshJumpPtr != "" {
+		tunnel, err := dialSSHJump(*sshJumpPtr)
 		if err != nil {
-			fmt.Printf("Error getting metadata for workspace %s: %v\n", workspace.Name, err)
-			continue
+			fmt.Fprintln(os.Stderr, "Error opening SSH tunnel:", err)
+			return 1
 		}
+		defer tunnel.Close()
+		dial = tunnel.DialContext
+		tunneled := http.DefaultTransport.(*http.Transport).Clone()
+		tunneled.Proxy = nil
+		tunneled.DialContext = dial
+		transport = tunneled
+	}
 
-		// Update counts for each meta field
-		updateCounts(meta.Counts, counts)
+	// Speak h2c to the admin API if specified
+	if *h2cPtr {
+		transport = h2cTransport(dial)
+	}
 
-		// Store workspace metadata
-		workspaceMetadata := WorkspaceMetadata{
-			WorkspaceName: workspace.Name,
-			Meta:          meta,
+	// Open a fresh connection for every request if specified
+	if *disableKeepalivePtr {
+		fresh := http.DefaultTransport.(*http.Transport).Clone()
+		fresh.DisableKeepAlives = true
+		if dial != nil {
+			fresh.Proxy = nil
+			fresh.DialContext = dial
 		}
-		workspaceMetadataList = append(workspaceMetadataList, workspaceMetadata)
+		transport = fresh
 	}
 
-	// Print individual workspace metadata if specified
-	if *metaPtr == "workspace" || *metaPtr == "all" {
-		fmt.Println("Individual Workspace Metadata:")
-		printWorkspaceMetadataTable(workspaceMetadataList)
+	// Record how long every request took if specified
+	if *timingsFilePtr != "" {
+		timings := &timingTransport{base: transport}
+		transport = timings
+		defer func() {
+			if err := timings.writeTimings(*timingsFilePtr); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing timings file:", err)
+				code = 1
+			}
+		}()
 	}
 
-	// Print total counts if specified
-	if *metaPtr == "counts" || *metaPtr == "all" {
-		fmt.Println("Total Meta Field Counts:")
-		printCountsTable(counts, len(workspaceMetadataList))
+	// Log every admin API call if specified, below the adaptive limiter so
+	// the latencies leave out the time spent waiting for a slot
+	if *verbosePtr || *dumpHeadersPtr {
+		logged := &verboseTransport{base: transport, w: os.Stderr, headers: *dumpHeadersPtr}
+		transport = logged
+		defer logged.writeLatencySummary()
 	}
-}
 
-func getWorkspaces(url string) ([]Workspace, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
+	// Adapt the number of requests in flight to the server if specified
+	if *adaptivePtr {
+		var w io.Writer
+		if *verbosePtr {
+			w = os.Stderr
+		}
+		transport = newAdaptiveTransport(transport, *concurrencyPtr, w)
+	}
+	client.HTTP.Transport = transport
+
+	// Trace every admin API call if specified
+	ctx := context.Background()
+	if *otelPtr {
+		shutdown, err := setupTracing(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error setting up tracing:", err)
+			return 1
+		}
+		defer shutdown(ctx)
+		client.HTTP.Transport = tracingTransport(transport)
+	}
+
+	// Show the effective settings before running if specified
+	if *printConfigPtr {
+		set := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+		outputSource := "default"
+		if set["output"] {
+			outputSource = "flag"
+		} else if os.Getenv("KONG_WS_OUTPUT") != "" {
+			outputSource = "env KONG_WS_OUTPUT"
+		}
+
+		addrs := *urlPtr
+		if multiCluster {
+			addrs = kongAddrs.String()
+		}
+		settings := []configSetting{
+			{Name: "kong-addr", Value: addrs, Source: addrSource},
+			{Name: "token", Value: redact(*tokenPtr), Source: tokenSource},
+		}
+		for _, name := range []string{"concurrency", "per-request-timeout", "list-timeout", "meta-timeout", "deadline", "retries", "retry-budget", "retry-status", "max-requests", "meta"} {
+			setting := flagSetting(name, set)
+			if (name == "list-timeout" || name == "meta-timeout") && !set[name] && (set["per-request-timeout"] || timeoutSource != "") {
+				setting.Source = "per-request-timeout"
+			}
+			if name == "per-request-timeout" && timeoutSource != "" {
+				setting.Source = timeoutSource
+			}
+			if name == "concurrency" && !set[name] && *adaptivePtr {
+				setting.Source = "adaptive"
+			}
+			settings = append(settings, setting)
+		}
+		settings = append(settings, configSetting{Name: "output", Value: *outputPtr, Source: outputSource})
+		settings = append(settings, headerSettings(client.Headers)...)
+		printConfig(os.Stderr, settings)
+	}
+
+	// Run the connectivity checklist instead of collecting if specified
+	if *diagnosePtr {
+		if !printDiagnostics(os.Stdout, runDiagnostics(ctx, client, *urlPtr)) {
+			return 1
+		}
+		return 0
+	}
+
+	opts := RenderOptions{
+		Meta:              *metaPtr,
+		TopFields:         *topFieldsPtr,
+		Quiet:             *quietPtr,
+		Proportion:        *proportionPtr,
+		MinCount:          *minCountPtr,
+		Wide:              *widePtr,
+		TermWidth:         terminalWidth(os.Stdout),
+		NoHeaders:         *noHeadersPtr,
+		NoBorders:         *noBordersPtr,
+		Color:             !*noColorPtr && colorSupported(os.Stdout),
+		InfluxMeasurement: *influxMeasurementPtr,
+		InfluxTags:        influxTagMap,
+		Weights:           weights,
+		Pretty:            *prettyPtr,
+		ExcludeColumns:    excludedColumns,
+		KeyByID:           *keyByIDPtr,
+		Transpose:         *transposePtr,
+	}
+	if *declarativeFilePtr == "" && *fromJSONPtr == "" {
+		opts.ClusterAddr = *urlPtr
+	}
+	if *humanPtr {
+		opts.Human = "separators"
+	}
+	if *humanSIPtr {
+		opts.Human = "si"
+	}
+
+	// Copy everything printed to stdout to the clipboard if specified
+	var stdout io.Writer = os.Stdout
+	if *clipboardPtr {
+		var copied bytes.Buffer
+		stdout = io.MultiWriter(os.Stdout, &copied)
+		defer func() {
+			if copied.Len() == 0 {
+				return
+			}
+			if err := copyToClipboard(copied.String()); err != nil {
+				fmt.Fprintln(os.Stderr, "Error copying to clipboard:", err)
+				code = 1
+			}
+		}()
+	}
+
+	// checkVersion warns once per address when the Kong version is outside
+	// the tested range. A version that cannot be read is not worth a warning.
+	// The probe is not part of the scan, so it is left out of --max-requests
+	// and --bench.
+	var versionChecked sync.Map
+	checkVersion := func(ctx context.Context, addr string) {
+		if _, checked := versionChecked.LoadOrStore(addr, true); checked {
+			return
+		}
+		identity, err := getClusterIdentity(uncounted(ctx), client, addr)
+		if err != nil {
+			return
+		}
+		if warning := kongVersionWarning(identity.Version); warning != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s (%s)\n", warning, addr)
+		}
+	}
+
+	// listClusterWorkspaces lists the workspaces of the cluster at addr and
+	// narrows them down to the ones the run covers
+	listClusterWorkspaces := func(ctx context.Context, addr string) ([]Workspace, error) {
+		workspaces, err := getWorkspaces(ctx, client, addr+"/workspaces", *listTimeoutPtr, *maxPagesPtr)
+		if errors.Is(err, errPageLimit) {
+			fmt.Fprintf(os.Stderr, "Warning: stopped listing workspaces of %s after %d pages (--max-pages), results may be incomplete\n", addr, *maxPagesPtr)
+		} else if err != nil {
+			return nil, fmt.Errorf("getting workspaces: %w", err)
+		}
+
+		// Fail if any required workspace is missing
+		if *requirePtr != "" {
+			if missing := missingWorkspaces(workspaces, strings.Split(*requirePtr, ",")); len(missing) > 0 {
+				return nil, fmt.Errorf("required workspaces not found: %s", strings.Join(missing, ", "))
+			}
+		}
+
+		// Skip workspaces that have not changed recently if specified
+		if *sincePtr > 0 {
+			workspaces = filterModifiedSince(workspaces, time.Now().Add(-*sincePtr))
+		}
+
+		// Spread the load of concurrent scanners if specified
+		if *shufflePtr {
+			shuffleWorkspaces(workspaces, shuffleSeed)
+		}
+		return workspaces, nil
+	}
+
+	// collectClusterMetadata fetches the metadata of the listed workspaces,
+	// returning the failed ones alongside it
+	collectClusterMetadata := func(ctx context.Context, addr string, workspaces []Workspace, raw bool) ([]WorkspaceMetadata, *CollectError) {
+		collectOpts := CollectOptions{
+			MetaValues:        metaValues,
+			Concurrency:       *concurrencyPtr,
+			PerRequestTimeout: *metaTimeoutPtr,
+			Raw:               raw,
+			CountFallback:     *countFallbackPtr,
+			DetailConcurrency: *detailConcurrencyPtr,
+		}
+		workspaceMetadataList, err := collectMetadata(ctx, client, addr, workspaces, collectOpts)
+
+		// Workspaces without a meta endpoint are only counted if specified
+		var collectErr *CollectError
+		if errors.As(err, &collectErr) && *ignoreMissingMetaPtr {
+			var skipped int
+			collectErr, skipped = collectErr.withoutNotFound()
+			if skipped > 0 && !*quietPtr {
+				fmt.Fprintf(os.Stderr, "Skipped %d workspaces without a meta endpoint\n", skipped)
+			}
+		}
+
+		// Say how far the run got if the request budget ran out
+		if errors.Is(err, errRequestBudget) {
+			fmt.Fprintf(os.Stderr, "Request budget of %d reached: covered %d of %d workspaces\n", *maxRequestsPtr, len(workspaceMetadataList), len(workspaces))
+		}
+		return workspaceMetadataList, collectErr
+	}
+
+	// buildReport turns the collected metadata into the report every output
+	// is written from. The cluster at addr is identified if specified; an
+	// empty addr means the metadata did not come from a cluster.
+	buildReport := func(ctx context.Context, addr string, workspaceMetadataList []WorkspaceMetadata, collectErr *CollectError) (Report, error) {
+		// Render rows in a stable order regardless of which request finished first
+		if err := sortWorkspaceMetadata(workspaceMetadataList, *sortWorkspacesPtr); err != nil {
+			return Report{}, err
+		}
+
+		// Collapse the matching workspaces into one entry if specified
+		if mergeGroup != nil {
+			workspaceMetadataList = mergeWorkspaces(workspaceMetadataList, mergeGroup, *mergeLabelPtr)
+		}
+
+		// Keep only the workspaces over a threshold if specified
+		if len(thresholds) > 0 {
+			workspaceMetadataList = filterOver(workspaceMetadataList, thresholds)
+		}
+
+		// Leave out the workspaces without entities if specified
+		if *hideEmptyPtr {
+			workspaceMetadataList = filterEmpty(workspaceMetadataList, emptyFields)
+		}
+
+		// Drop the excluded entity types from the tables and totals if specified
+		dropColumns(workspaceMetadataList, excludedColumns)
+
+		report := newReport(workspaceMetadataList, *excludeDefaultPtr, *defaultSeparatePtr)
+
+		// Label the report with the node it came from if specified
+		if *identityPtr && addr != "" {
+			identity, err := getClusterIdentity(ctx, client, addr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not identify the cluster at %s: %v\n", addr, err)
+			}
+			report.Cluster = identity
+		}
+
+		// Anonymize workspace names if specified. Labels follow the sorted
+		// order, so the map is rewritten on every scan.
+		if *anonymizePtr {
+			mapping := anonymizeReport(&report, collectErr)
+			if *anonymizeMapPtr != "" {
+				if err := writeAnonymizeMap(*anonymizeMapPtr, mapping); err != nil {
+					return Report{}, fmt.Errorf("writing anonymize map: %w", err)
+				}
+			}
+		}
+		return report, nil
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	// scanCluster collects the report of the cluster at addr, returning the
+	// failed workspaces alongside it
+	scanCluster := func(ctx context.Context, addr string) (Report, *CollectError, error) {
+		ctx, span := otel.Tracer(tracerName).Start(ctx, "collect")
+		defer span.End()
+		checkVersion(ctx, addr)
+
+		// Stop issuing requests once the overall deadline passes if specified
+		collectCtx := ctx
+		if *deadlinePtr > 0 {
+			var cancel context.CancelFunc
+			collectCtx, cancel = context.WithTimeout(ctx, *deadlinePtr)
+			defer cancel()
+		}
+
+		workspaces, err := listClusterWorkspaces(collectCtx, addr)
+		if err != nil {
+			return Report{}, nil, err
+		}
+		workspaceMetadataList, collectErr := collectClusterMetadata(collectCtx, addr, workspaces, false)
+		report, err := buildReport(ctx, addr, workspaceMetadataList, collectErr)
+		if err != nil {
+			return Report{}, nil, err
+		}
+		return report, collectErr, nil
+	}
+
+	// Scan every cluster and print one section each if specified
+	if multiCluster {
+		clusters := scanClusters(ctx, kongAddrs, *clusterConcurrencyPtr, scanCluster)
+		if err := writeClusterReports(stdout, *outputPtr, opts, clusters); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing output:", err)
+			return 1
+		}
+		for _, cluster := range clusters {
+			if cluster.Report == nil {
+				return 1
+			}
+		}
+		return 0
+	}
+
+	// Run as a Prometheus exporter until interrupted if specified
+	if *servePtr != "" {
+		handler := &metricsHandler{
+			minInterval: *serveCachePtr,
+			collect: func(ctx context.Context) (Report, error) {
+				report, collectErr, err := scanCluster(ctx, *urlPtr)
+				if collectErr != nil && ctx.Err() == nil {
+					printErrorsTable(os.Stderr, collectErr)
+				}
+				return report, err
+			},
+		}
+
+		serveCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if !*quietPtr {
+			fmt.Fprintf(os.Stderr, "Serving metrics at http://%s/metrics\n", *servePtr)
+		}
+		if err := serveMetrics(serveCtx, *servePtr, handler); err != nil {
+			fmt.Fprintln(os.Stderr, "Error serving metrics:", err)
+			return 1
+		}
+		return 0
+	}
+
+	// Re-collect and redraw on an interval until interrupted if specified
+	if *watchPtr > 0 {
+		first := true
+		var previous *Report
+		collect := func(ctx context.Context) (Report, error) {
+			// Pick up a rotated token from the credential helper
+			if *tokenCommandPtr != "" && !first {
+				token, err := runTokenCommand(ctx, *tokenCommandPtr)
+				if err != nil {
+					return Report{}, fmt.Errorf("running --token-command: %w", err)
+				}
+				client.Headers.Set("Kong-Admin-Token", token)
+			}
+			first = false
+
+			report, collectErr, err := scanCluster(ctx, *urlPtr)
+			// Failures caused by an interrupt are not worth reporting
+			if collectErr != nil && ctx.Err() == nil {
+				printErrorsTable(os.Stderr, collectErr)
+			}
+
+			// Log what changed since the previous cycle if specified
+			if *changeLogPtr != "" && err == nil && ctx.Err() == nil {
+				if previous != nil {
+					if err := appendChangeLog(*changeLogPtr, time.Now(), diffReports(*previous, report)); err != nil {
+						fmt.Fprintln(os.Stderr, "Error writing change log:", err)
+					}
+				}
+				previous = &report
+			}
+			return report, err
+		}
+
+		watchCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+		if err := runWatch(watchCtx, os.Stdout, *watchPtr, *outputPtr, *sparklinePtr, opts, collect); err != nil && !errors.Is(err, syscall.EPIPE) {
+			fmt.Fprintln(os.Stderr, "Error writing output:", err)
+			return 1
+		}
+		return 0
+	}
+
+	// printBench reports the collection throughput in place of the output
+	collectStart := time.Now()
+	printBench := func(collected int) {
+		elapsed := time.Since(collectStart)
+		fmt.Fprintf(stdout, "Collected %d workspaces with %d requests in %s (%.1f workspaces/s, %.1f requests/s)\n",
+			collected, client.Requests(), elapsed.Round(time.Millisecond),
+			float64(collected)/elapsed.Seconds(), float64(client.Requests())/elapsed.Seconds())
+	}
+
+	var report Report
+	var collectErr *CollectError
+	if *fromJSONPtr != "" || *declarativeFilePtr != "" {
+		var workspaceMetadataList []WorkspaceMetadata
+		var savedCluster *ClusterIdentity
+		if *fromJSONPtr != "" {
+			// Re-render a previously saved report instead of asking Kong
+			saved, err := loadReport(*fromJSONPtr)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error reading saved report:", err)
+				return 1
+			}
+			workspaceMetadataList = saved.Workspaces
+			if saved.Default != nil {
+				workspaceMetadataList = append([]WorkspaceMetadata{*saved.Default}, workspaceMetadataList...)
+			}
+			savedCluster = saved.Cluster
+		} else {
+			// Count the entities of a declarative config instead of asking Kong
+			workspaceMetadataList, err = loadDeclarativeMetadata(*declarativeFilePtr)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error reading declarative config:", err)
+				return 1
+			}
+		}
+
+		// Only report the collection throughput if specified
+		if *benchPtr {
+			printBench(len(workspaceMetadataList))
+			return 0
+		}
+
+		report, err = buildReport(ctx, "", workspaceMetadataList, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+
+		// Keep the identity recorded in a saved report
+		report.Cluster = savedCluster
+	} else if !*checkAccessPtr && !*byTagPtr && !*pluginsInventoryPtr && !*pluginsByScopePtr && !*pluginsDetailPtr && !*rawPtr && !*benchPtr {
+		report, collectErr, err = scanCluster(ctx, *urlPtr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+
+		// Failed workspaces are listed after the output, under the labels
+		// given by --anonymize since the table is only printed on return
+		if collectErr != nil {
+			defer printErrorsTable(os.Stderr, collectErr)
+		}
+	} else {
+		// The remaining modes report something other than the counts, so
+		// they share only the listing and collection of the scan
+		ctx, span := otel.Tracer(tracerName).Start(ctx, "collect")
+		defer span.End()
+
+		// Stop issuing requests once the overall deadline passes if specified
+		collectCtx := ctx
+		if *deadlinePtr > 0 {
+			var cancel context.CancelFunc
+			collectCtx, cancel = context.WithTimeout(ctx, *deadlinePtr)
+			defer cancel()
+		}
+
+		checkVersion(collectCtx, *urlPtr)
+		workspaces, err := listClusterWorkspaces(collectCtx, *urlPtr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+
+		// Only probe which workspaces the credentials can read if specified
+		if *checkAccessPtr {
+			access := checkAccess(collectCtx, client, *urlPtr, workspaces, metaValues, *concurrencyPtr)
+			span.End()
+
+			if err := writeAccess(stdout, *outputPtr, *quietPtr, access); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing output:", err)
+				return 1
+			}
+			for _, workspace := range access {
+				if !workspace.Accessible {
+					return 1
+				}
+			}
+			return 0
+		}
+
+		// Aggregate entity counts per tag instead of per workspace if specified
+		if *byTagPtr {
+			tagCounts, err := collectTagCounts(collectCtx, client, *urlPtr, workspaces, *concurrencyPtr, *detailConcurrencyPtr)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error counting entities by tag:", err)
+				return 1
+			}
+			span.End()
+
+			if err := writeTagCounts(stdout, *outputPtr, *quietPtr, tagCounts); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing output:", err)
+				return 1
+			}
+			return 0
+		}
+
+		// Inventory plugins by installed version and protocols if specified
+		if *pluginsInventoryPtr {
+			inventory, err := collectPluginInventory(collectCtx, client, *urlPtr, workspaces, *concurrencyPtr)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error collecting plugin inventory:", err)
+				return 1
+			}
+			span.End()
+
+			if err := writePluginInventory(stdout, *outputPtr, *quietPtr, inventory); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing output:", err)
+				return 1
+			}
+			return 0
+		}
+
+		// Break the plugin count down by scope if specified
+		if *pluginsByScopePtr {
+			scopes, err := collectPluginScopes(collectCtx, client, *urlPtr, workspaces, *concurrencyPtr)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error collecting plugin scopes:", err)
+				return 1
+			}
+			span.End()
+
+			if err := writePluginScopes(stdout, *outputPtr, *quietPtr, scopes); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing output:", err)
+				return 1
+			}
+			return 0
+		}
+
+		// Break the plugin count down by plugin name if specified
+		if *pluginsDetailPtr {
+			breakdown, err := collectPluginBreakdown(collectCtx, client, *urlPtr, workspaces, *enabledOnlyPtr, *concurrencyPtr)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error collecting plugin breakdown:", err)
+				return 1
+			}
+			span.End()
+
+			if err := writePluginBreakdown(stdout, *outputPtr, *quietPtr, breakdown); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing output:", err)
+				return 1
+			}
+			return 0
+		}
+
+		workspaceMetadataList, collectErr := collectClusterMetadata(collectCtx, *urlPtr, workspaces, *rawPtr)
+		if collectErr != nil {
+			defer printErrorsTable(os.Stderr, collectErr)
+		}
+		span.End()
+
+		// Only report the collection throughput if specified
+		if *benchPtr {
+			printBench(len(workspaceMetadataList))
+			return 0
+		}
+
+		// Print the responses exactly as Kong returned them
+		if err := sortWorkspaceMetadata(workspaceMetadataList, *sortWorkspacesPtr); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 2
+		}
+		if err := writeRaw(stdout, workspaceMetadataList); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing output:", err)
+			return 1
+		}
+		return 0
+	}
+
+	// Write one file per workspace if specified
+	if *splitDirPtr != "" {
+		if err := writeSplitFiles(*splitDirPtr, report); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing split files:", err)
+			return 1
+		}
+	}
+
+	// Write a spreadsheet of the counts if specified
+	if *xlsxPtr != "" {
+		if err := writeXLSX(*xlsxPtr, report, excludedColumns); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing spreadsheet:", err)
+			return 1
+		}
+	}
+
+	// Add the result to the history file if specified
+	if *appendFilePtr != "" {
+		if err := appendHistory(*appendFilePtr, report); err != nil {
+			fmt.Fprintln(os.Stderr, "Error appending to history file:", err)
+			return 1
+		}
+	}
+
+	// Push the result to the webhook if specified
+	if *webhookPtr != "" {
+		status, err := postWebhook(ctx, *webhookPtr, webhookHeaderValues, report)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error posting webhook:", err)
+			return 1
+		}
+		if !*quietPtr {
+			fmt.Fprintln(os.Stderr, "Webhook response:", status)
+		}
+	}
+
+	// Send the counts to the StatsD agent if specified
+	if *statsdPtr != "" {
+		if err := pushStatsd(*statsdPtr, *statsdPrefixPtr, statsdTags, report); err != nil {
+			fmt.Fprintln(os.Stderr, "Error sending to StatsD:", err)
+			return 1
+		}
+	}
+
+	// Show which entity types only some workspaces use if specified
+	if *coveragePtr {
+		if err := writeCoverage(stdout, *outputPtr, opts, entityCoverage(report, excludedColumns)); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing output:", err)
+			return 1
+		}
+		return 0
+	}
+
+	// Print only a hash of the counts for cheap change detection if specified
+	if *checksumPtr {
+		checksum, err := reportChecksum(report)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error computing checksum:", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, checksum)
+		return 0
+	}
+
+	// Browse interactively instead of printing if specified
+	if *tuiPtr {
+		if err := runTUI(report); err != nil {
+			fmt.Fprintln(os.Stderr, "Error running TUI:", err)
+			return 1
+		}
+		return 0
+	}
+
+	// Print only what changed since the baseline if specified
+	if *baselinePtr != "" {
+		changes := diffReports(baseline, report)
+		title, none := "Changes Since Baseline", "No changes since baseline."
+
+		// Keep only the growth over the alert thresholds if specified
+		if len(alertDeltas) > 0 {
+			changes = filterGrowth(changes, alertDeltas)
+			title, none = "Growth Over Alert Delta", "No growth over the alert delta since baseline."
+		}
+
+		if err := writeDiff(stdout, *outputPtr, *quietPtr, title, none, changes); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing output:", err)
+			return 1
+		}
+		// Trip if the cluster lost too many entities since the baseline
+		if *minTotalRatioPtr > 0 {
+			before, after := sumCounts(baseline.Totals), sumCounts(report.Totals)
+			if before > 0 && float64(after) < *minTotalRatioPtr*float64(before) {
+				fmt.Fprintf(os.Stderr, "Error: total entity count dropped to %.1f%% of the baseline (%d -> %d), below --min-total-ratio %g\n",
+					100*float64(after)/float64(before), before, after, *minTotalRatioPtr)
+				printShrunkTotals(os.Stderr, shrunkTotals(baseline, report))
+				return exitDiff
+			}
+		}
+
+		// Fail the run on any difference or alert if specified
+		if (*failOnDiffPtr || len(alertDeltas) > 0) && len(changes) > 0 {
+			return exitDiff
+		}
+		return 0
+	}
+
+	// Render the custom template instead of a built-in format if specified
+	if formatTemplate != nil {
+		if err := writeTemplate(stdout, formatTemplate, report); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing output:", err)
+			return 1
+		}
+		return 0
+	}
+
+	// With --tee the table goes to stdout and a JSON copy goes to the file
+	if *teePtr {
+		if err := writeOutput(stdout, "table", opts, report); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing output:", err)
+			return 1
+		}
+		if err := writeOutputFile(*outFilePtr, "json", opts, report, *gzipPtr); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing output file:", err)
+			return 1
+		}
+		return 0
+	}
+
+	if *outFilePtr != "" {
+		err = writeOutputFile(*outFilePtr, *outputPtr, opts, report, *gzipPtr)
+	} else {
+		err = writeOutput(stdout, *outputPtr, opts, report)
+	}
+	// A consumer that stopped reading early, as in "| head", is not an error
+	if errors.Is(err, syscall.EPIPE) {
+		return 0
+	}
 	if err != nil {
-		return nil, err
+		fmt.Fprintln(os.Stderr, "Error writing output:", err)
+		return 1
+	}
+	return 0
+}
+
+// envOrDefault returns the value of the environment variable key, or
+// fallback when it is unset or empty.
+func envOrDefault(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// parseQueryValues converts key=value pairs into url.Values.
+func parseQueryValues(pairs []string) (url.Values, error) {
+	values := url.Values{}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid key=value pair %q", pair)
+		}
+		values.Add(key, value)
+	}
+	return values, nil
+}
+
+// missingWorkspaces returns the required names that are not in workspaces.
+func missingWorkspaces(workspaces []Workspace, required []string) []string {
+	present := make(map[string]bool, len(workspaces))
+	for _, workspace := range workspaces {
+		present[workspace.Name] = true
 	}
 
-	var response WorkspaceResponse
-	err = json.Unmarshal(body, &response)
+	missing := make([]string, 0)
+	for _, name := range required {
+		name = strings.TrimSpace(name)
+		if name != "" && !present[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// shuffleWorkspaces puts the workspaces in a random order determined by seed.
+func shuffleWorkspaces(workspaces []Workspace, seed int64) {
+	random := rand.New(rand.NewSource(seed))
+	random.Shuffle(len(workspaces), func(i, j int) {
+		workspaces[i], workspaces[j] = workspaces[j], workspaces[i]
+	})
+}
+
+// filterModifiedSince keeps the workspaces modified at or after cutoff.
+// Workspaces without any timestamp are kept since their age is unknown.
+func filterModifiedSince(workspaces []Workspace, cutoff time.Time) []Workspace {
+	recent := make([]Workspace, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		if workspace.CreatedAt == 0 && workspace.UpdatedAt == 0 {
+			recent = append(recent, workspace)
+			continue
+		}
+		if !workspace.modifiedAt().Before(cutoff) {
+			recent = append(recent, workspace)
+		}
+	}
+	return recent
+}
+
+// parseThresholds converts entity=N pairs into a map of entity to count.
+func parseThresholds(pairs []string) (map[string]int, error) {
+	values, err := parseQueryValues(pairs)
 	if err != nil {
 		return nil, err
 	}
 
-	return response.Data, nil
+	thresholds := make(map[string]int, len(values))
+	for entity := range values {
+		threshold, err := strconv.Atoi(values.Get(entity))
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold for %s: %q", entity, values.Get(entity))
+		}
+		thresholds[entity] = threshold
+	}
+	return thresholds, nil
 }
 
-func getMetadata(url string, headers string) (Metadata, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return Metadata{}, err
+// filterOver keeps the workspaces with more of some entity than its
+// threshold.
+func filterOver(metadataList []WorkspaceMetadata, thresholds map[string]int) []WorkspaceMetadata {
+	over := make([]WorkspaceMetadata, 0, len(metadataList))
+	for _, metadata := range metadataList {
+		for entity, threshold := range thresholds {
+			if metadata.Meta.Counts[entity] > threshold {
+				over = append(over, metadata)
+				break
+			}
+		}
 	}
+	return over
+}
 
-	// Add headers if provided
-	if headers != "" {
-		headerArr := strings.Split(headers, ":")
-		if len(headerArr) == 2 {
-			req.Header.Set(strings.TrimSpace(headerArr[0]), strings.TrimSpace(headerArr[1]))
+// parseColumnList turns a comma-separated list of entity types into a set.
+func parseColumnList(list string) map[string]bool {
+	columns := make(map[string]bool)
+	for _, column := range strings.Split(list, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns[column] = true
 		}
 	}
+	return columns
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return Metadata{}, err
+// dropColumns removes the excluded entity types from every workspace's
+// counts, so they are left out of the totals as well as the tables.
+func dropColumns(metadataList []WorkspaceMetadata, excluded map[string]bool) {
+	for _, metadata := range metadataList {
+		for column := range excluded {
+			delete(metadata.Meta.Counts, column)
+		}
 	}
-	defer resp.Body.Close()
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return Metadata{}, err
+// filterEmpty drops the workspaces whose counts of the given fields are all
+// zero, or whose counts are all zero when no fields are given.
+func filterEmpty(metadataList []WorkspaceMetadata, fields map[string]bool) []WorkspaceMetadata {
+	kept := make([]WorkspaceMetadata, 0, len(metadataList))
+	for _, metadata := range metadataList {
+		for field, count := range metadata.Meta.Counts {
+			if count != 0 && (len(fields) == 0 || fields[field]) {
+				kept = append(kept, metadata)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// mergeWorkspaces replaces the workspaces whose name matches group with a
+// single entry named label holding their summed counts, at the position of
+// the first match.
+func mergeWorkspaces(metadataList []WorkspaceMetadata, group *regexp.Regexp, label string) []WorkspaceMetadata {
+	merged := make([]WorkspaceMetadata, 0, len(metadataList))
+	index := -1
+	for _, metadata := range metadataList {
+		if !group.MatchString(metadata.WorkspaceName) {
+			merged = append(merged, metadata)
+			continue
+		}
+		if index < 0 {
+			index = len(merged)
+			merged = append(merged, WorkspaceMetadata{WorkspaceName: label, Meta: Metadata{Counts: make(map[string]int)}})
+		}
+		updateCounts(metadata.Meta.Counts, merged[index].Meta.Counts)
+	}
+	return merged
+}
+
+// removeWorkspace returns the list without the named workspace, along with
+// the removed entry or nil when it was not present.
+func removeWorkspace(metadataList []WorkspaceMetadata, name string) ([]WorkspaceMetadata, *WorkspaceMetadata) {
+	remaining := make([]WorkspaceMetadata, 0, len(metadataList))
+	var removed *WorkspaceMetadata
+	for i := range metadataList {
+		if metadataList[i].WorkspaceName == name {
+			removed = &metadataList[i]
+			continue
+		}
+		remaining = append(remaining, metadataList[i])
+	}
+	return remaining, removed
+}
+
+// anonymizeReport replaces each workspace name in the report, the default
+// workspace first, and in the failed workspaces with a sequential label
+// (ws-001, ws-002, ...) and returns the real-to-anonymized name mapping.
+// collectErr may be nil.
+func anonymizeReport(report *Report, collectErr *CollectError) map[string]string {
+	mapping := make(map[string]string)
+	rename := func(name *string) {
+		label, ok := mapping[*name]
+		if !ok {
+			label = fmt.Sprintf("ws-%03d", len(mapping)+1)
+			mapping[*name] = label
+		}
+		*name = label
+	}
+
+	if report.Default != nil {
+		rename(&report.Default.WorkspaceName)
+	}
+	for i := range report.Workspaces {
+		rename(&report.Workspaces[i].WorkspaceName)
+	}
+	if collectErr != nil {
+		for i := range collectErr.Failures {
+			rename(&collectErr.Failures[i].Workspace)
+		}
 	}
+	return mapping
+}
 
-	var metadata Metadata
-	err = json.Unmarshal(body, &metadata)
+func writeAnonymizeMap(path string, mapping map[string]string) error {
+	data, err := json.MarshalIndent(mapping, "", "  ")
 	if err != nil {
-		return Metadata{}, err
+		return err
 	}
+	return ioutil.WriteFile(path, data, 0600)
+}
 
-	return metadata, nil
+// newReport builds the report from the collected metadata, setting the
+// default workspace aside or dropping it as requested and totalling the rest.
+func newReport(metadataList []WorkspaceMetadata, excludeDefault bool, defaultSeparate bool) Report {
+	var defaultMetadata *WorkspaceMetadata
+	if excludeDefault || defaultSeparate {
+		metadataList, defaultMetadata = removeWorkspace(metadataList, "default")
+		if !defaultSeparate {
+			defaultMetadata = nil
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, workspaceMetadata := range metadataList {
+		updateCounts(workspaceMetadata.Meta.Counts, counts)
+	}
+	return Report{Workspaces: metadataList, Totals: counts, Default: defaultMetadata}
 }
 
 func updateCounts(metaCounts map[string]int, counts map[string]int) {
@@ -161,24 +1573,144 @@ func updateCounts(metaCounts map[string]int, counts map[string]int) {
 	}
 }
 
-func printWorkspaceMetadataTable(metadataList []WorkspaceMetadata) {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Workspace Name", "Plugins", "Targets", "Services", "Routes", "Upstreams"})
+func printWorkspaceMetadataTable(w io.Writer, metadataList []WorkspaceMetadata, opts RenderOptions) {
+	columns := metadataColumns(metadataList, opts.ExcludeColumns)
+	header := append([]string{"Workspace Name"}, columnTitles(columns)...)
+	if len(opts.Weights) > 0 {
+		header = append(header, "Score")
+	}
 
+	rows := make([][]string, 0, len(metadataList))
 	for _, metadata := range metadataList {
-		plugins := strconv.Itoa(metadata.Meta.Counts["plugins"])
-		targets := strconv.Itoa(metadata.Meta.Counts["targets"])
-		services := strconv.Itoa(metadata.Meta.Counts["services"])
-		routes := strconv.Itoa(metadata.Meta.Counts["routes"])
-		upstreams := strconv.Itoa(metadata.Meta.Counts["upstreams"])
+		previous := opts.previousCounts(metadata.WorkspaceName)
+		row := []string{metadata.WorkspaceName}
+		for _, column := range columns {
+			row = append(row, opts.formatChange(metadata.Meta.Counts[column], previous[column]))
+		}
+		if len(opts.Weights) > 0 {
+			row = append(row, formatScore(weightedScore(metadata.Meta.Counts, opts.Weights)))
+		}
+		rows = append(rows, row)
+	}
 
-		table.Append([]string{metadata.WorkspaceName, plugins, targets, services, routes, upstreams})
+	// Put entity types in rows and workspaces in columns if specified
+	if opts.Transpose {
+		header[0] = "Meta Field"
+		header, rows = transpose(header, rows)
 	}
 
+	// Render off-screen first so the width can be checked
+	var buf bytes.Buffer
+	table := opts.newTable(&buf, header)
+	if opts.Human != "" || opts.Previous != nil {
+		alignCounts(table, len(header))
+	}
+	for _, row := range rows {
+		table.Append(row)
+	}
 	table.Render()
+
+	// Fall back to a vertical layout when the table would wrap
+	if !opts.Wide && opts.TermWidth > 0 && maxLineWidth(buf.String()) > opts.TermWidth {
+		if !opts.Quiet {
+			fmt.Fprintln(os.Stderr, "Table is wider than the terminal, showing a vertical layout (use --wide to force the table or --output json)")
+		}
+		printWorkspaceMetadataVertical(w, metadataList, columns, opts)
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+// transpose swaps the rows and columns of a table, the header included, so
+// the first column becomes the new header.
+func transpose(header []string, rows [][]string) ([]string, [][]string) {
+	transposed := make([][]string, len(header))
+	for i := range header {
+		transposed[i] = make([]string, 0, len(rows)+1)
+		transposed[i] = append(transposed[i], header[i])
+		for _, row := range rows {
+			transposed[i] = append(transposed[i], row[i])
+		}
+	}
+	return transposed[0], transposed[1:]
+}
+
+// printWorkspaceMetadataVertical prints one block per workspace with a line
+// per field, for terminals too narrow for the full table.
+func printWorkspaceMetadataVertical(w io.Writer, metadataList []WorkspaceMetadata, columns []string, opts RenderOptions) {
+	width := 0
+	for _, column := range columns {
+		if len(column) > width {
+			width = len(column)
+		}
+	}
+
+	for i, metadata := range metadataList {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		previous := opts.previousCounts(metadata.WorkspaceName)
+		fmt.Fprintln(w, metadata.WorkspaceName)
+		for _, column := range columns {
+			fmt.Fprintf(w, "  %-*s  %s\n", width, column, opts.formatChange(metadata.Meta.Counts[column], previous[column]))
+		}
+		if len(opts.Weights) > 0 {
+			fmt.Fprintf(w, "  %-*s  %s\n", width, "score", formatScore(weightedScore(metadata.Meta.Counts, opts.Weights)))
+		}
+	}
+}
+
+// defaultColumns are the entity types shown first in the per-workspace table,
+// in their historical order.
+var defaultColumns = []string{"plugins", "targets", "services", "routes", "upstreams"}
+
+// metadataColumns returns the per-workspace table columns: the default
+// entity types followed by any other field reported by a workspace, sorted.
+// Excluded entity types are never returned.
+func metadataColumns(metadataList []WorkspaceMetadata, excluded map[string]bool) []string {
+	seen := make(map[string]bool)
+	for column := range excluded {
+		seen[column] = true
+	}
+	columns := make([]string, 0, len(defaultColumns))
+	for _, column := range defaultColumns {
+		if seen[column] {
+			continue
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+
+	extra := make([]string, 0)
+	for _, metadata := range metadataList {
+		for field := range metadata.Meta.Counts {
+			if !seen[field] {
+				seen[field] = true
+				extra = append(extra, field)
+			}
+		}
+	}
+	sort.Strings(extra)
+	return append(columns, extra...)
 }
 
-func printCountsTable(counts map[string]int, workspaceCount int) {
+// columnTitles turns field names into table headers, e.g. "key_sets" into
+// "Key Sets".
+func columnTitles(columns []string) []string {
+	titles := make([]string, len(columns))
+	for i, column := range columns {
+		words := strings.Fields(strings.ReplaceAll(column, "_", " "))
+		for j, word := range words {
+			words[j] = strings.ToUpper(word[:1]) + word[1:]
+		}
+		titles[i] = strings.Join(words, " ")
+	}
+	return titles
+}
+
+func printCountsTable(w io.Writer, counts map[string]int, workspaceCount int, opts RenderOptions) {
+	topFields := opts.TopFields
+
 	// Create a slice of struct to hold the field and count information
 	type MetaField struct {
 		Field string
@@ -187,31 +1719,77 @@ func printCountsTable(counts map[string]int, workspaceCount int) {
 
 	metaFields := make([]MetaField, 0, len(counts))
 
-	// Convert the map to a slice of MetaField structs
+	// Convert the map to a slice of MetaField structs, dropping fields below
+	// the minimum count
 	for field, count := range counts {
+		if count < opts.MinCount {
+			continue
+		}
 		metaFields = append(metaFields, MetaField{Field: field, Count: count})
 	}
 
+	// Keep only the N highest-count fields and fold the rest into "others"
+	others := -1
+	if topFields > 0 && len(metaFields) > topFields {
+		sort.Slice(metaFields, func(i, j int) bool {
+			if metaFields[i].Count != metaFields[j].Count {
+				return metaFields[i].Count > metaFields[j].Count
+			}
+			return metaFields[i].Field < metaFields[j].Field
+		})
+
+		others = 0
+		for _, metaField := range metaFields[topFields:] {
+			others += metaField.Count
+		}
+		metaFields = metaFields[:topFields]
+	}
+
 	// Sort the metaFields slice based on the count in ascending order
 	sort.Slice(metaFields, func(i, j int) bool {
 		return metaFields[i].Count < metaFields[j].Count
 	})
 
 	// Print the sorted meta fields table
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Meta Field", "Count"})
+	table := opts.newTable(w, []string{"Meta Field", "Count"})
+	if opts.Human != "" || opts.Previous != nil {
+		alignCounts(table, 2)
+	}
+
+	// Compare against the previous watch cycle if there is one
+	previousTotals := map[string]int{}
+	previousWorkspaceCount := workspaceCount
+	if opts.Previous != nil {
+		previousTotals = opts.Previous.Totals
+		previousWorkspaceCount = len(opts.Previous.Workspaces)
+	}
 
 	// Append the workspace count row to the table
-	table.Append([]string{"Workspaces", strconv.Itoa(workspaceCount)})
+	table.Append([]string{"Workspaces", opts.formatChange(workspaceCount, previousWorkspaceCount)})
 
 	// Append the meta fields rows to the table
 	for _, metaField := range metaFields {
 		row := []string{
 			metaField.Field,
-			strconv.Itoa(metaField.Count),
+			opts.formatChange(metaField.Count, previousTotals[metaField.Field]),
 		}
 		table.Append(row)
 	}
 
+	// Append the aggregated remainder when the table was limited
+	if others >= 0 {
+		table.Append([]string{"others", opts.formatCount(others)})
+	}
+
+	// Append the cluster-wide weighted score if weights are specified
+	if len(opts.Weights) > 0 {
+		table.Append([]string{"weighted score", formatScore(weightedScore(counts, opts.Weights))})
+	}
+
 	table.Render()
+
+	// Show each field's share of the total if specified
+	if opts.Proportion {
+		printProportionBar(w, counts, opts.Color)
+	}
 }
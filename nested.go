@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// nestedLine is one row of the nested-text output.
+type nestedLine struct {
+	depth int
+	label string
+	count int
+}
+
+// writeNestedText prints the report as an indented tree of cluster,
+// workspace and entity type, each with its count in one aligned column.
+func writeNestedText(w io.Writer, opts RenderOptions, report Report) error {
+	cluster := "cluster"
+	if report.Cluster != nil {
+		cluster = report.Cluster.Hostname
+	} else if opts.ClusterAddr != "" {
+		cluster = opts.ClusterAddr
+	}
+
+	workspaces := report.Workspaces
+	if report.Default != nil {
+		workspaces = append(workspaces[:len(workspaces):len(workspaces)], *report.Default)
+	}
+
+	lines := []nestedLine{{depth: 0, label: cluster}}
+	for _, metadata := range workspaces {
+		workspace := len(lines)
+		lines = append(lines, nestedLine{depth: 1, label: metadata.WorkspaceName})
+		for _, field := range sortedKeys(metadata.Meta.Counts) {
+			count := metadata.Meta.Counts[field]
+			lines = append(lines, nestedLine{depth: 2, label: field, count: count})
+			lines[workspace].count += count
+		}
+	}
+
+	// The root counts what the totals count, leaving out a separate default
+	for _, count := range report.Totals {
+		lines[0].count += count
+	}
+
+	labelWidth, countWidth := 0, 0
+	for _, line := range lines {
+		labelWidth = max(labelWidth, 2*line.depth+len(line.label))
+		countWidth = max(countWidth, len(opts.formatCount(line.count)))
+	}
+
+	for _, line := range lines {
+		label := strings.Repeat("  ", line.depth) + line.label
+		if _, err := fmt.Fprintf(w, "%-*s  %*s\n", labelWidth, label, countWidth, opts.formatCount(line.count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
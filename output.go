@@ -0,0 +1,644 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/term"
+)
+
+// RenderOptions controls how the report is rendered.
+type RenderOptions struct {
+	Meta      string
+	TopFields int
+	// MinCount hides fields with a lower total from the counts table; they
+	// still count towards the proportion bar
+	MinCount int
+	Quiet    bool
+	// Human selects table number formatting: "" for raw integers,
+	// "separators" for thousands separators or "si" for SI suffixes.
+	Human string
+	// Proportion adds a stacked share bar below the counts table
+	Proportion bool
+	// Color enables ANSI colors in table output
+	Color bool
+	// TermWidth is the terminal width, zero when stdout is not a terminal
+	TermWidth int
+	// Wide keeps the full table even when it is wider than TermWidth
+	Wide bool
+	// NoHeaders and NoBorders strip tables down for awk/cut processing
+	NoHeaders bool
+	NoBorders bool
+	// Aligned draws tables as plain aligned columns instead of boxes
+	Aligned bool
+	// InfluxMeasurement and InfluxTags shape the influx line protocol output
+	InfluxMeasurement string
+	InfluxTags        map[string]string
+	// Weights adds a weighted score column and total row to tables
+	Weights map[string]float64
+	// Pretty highlights JSON output when Color is also set
+	Pretty bool
+	// ExcludeColumns are entity types kept out of the per-workspace table
+	ExcludeColumns map[string]bool
+	// Transpose puts workspaces in the columns of the per-workspace table
+	Transpose bool
+	// KeyByID keys the json output's workspaces by id instead of listing them
+	KeyByID bool
+	// ClusterAddr names the cluster in nested-text output when the report
+	// carries no identity; empty when the counts did not come from Kong
+	ClusterAddr string
+	// Previous is the report of the last --watch cycle; table counts show
+	// their change since it
+	Previous *Report
+}
+
+// rowTable is the part of tablewriter's API used by the report tables, so
+// they can also be drawn as plain aligned columns.
+type rowTable interface {
+	Append(row []string)
+	Render()
+}
+
+// newTable creates a table writer honoring the header, border and alignment
+// options.
+func (opts RenderOptions) newTable(w io.Writer, header []string) rowTable {
+	if opts.Aligned {
+		table := &alignedTable{w: w}
+		if !opts.NoHeaders {
+			for _, title := range header {
+				table.header = append(table.header, strings.ToUpper(title))
+			}
+		}
+		return table
+	}
+
+	table := tablewriter.NewWriter(w)
+	if !opts.NoHeaders {
+		table.SetHeader(header)
+	}
+	if opts.NoBorders {
+		table.SetBorder(false)
+		table.SetHeaderLine(false)
+		table.SetColumnSeparator("")
+		table.SetCenterSeparator("")
+		table.SetRowSeparator("")
+		table.SetTablePadding(" ")
+	}
+	return table
+}
+
+// formatCount renders a count for table output according to opts.Human.
+// Machine-readable formats always use raw integers.
+func (opts RenderOptions) formatCount(n int) string {
+	switch opts.Human {
+	case "separators":
+		return formatThousands(n)
+	case "si":
+		return formatSI(n)
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// terminalWidth returns the width of the terminal attached to file, or zero
+// when it is not a terminal.
+func terminalWidth(file *os.File) int {
+	if !term.IsTerminal(int(file.Fd())) {
+		return 0
+	}
+	width, _, err := term.GetSize(int(file.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// maxLineWidth returns the display width of the longest line in s.
+func maxLineWidth(s string) int {
+	width := 0
+	for _, line := range strings.Split(s, "\n") {
+		if lineWidth := runewidth.StringWidth(line); lineWidth > width {
+			width = lineWidth
+		}
+	}
+	return width
+}
+
+// alignedTable renders rows as columns separated by two spaces, without
+// box-drawing characters. The first column is left-aligned and the counts
+// after it are right-aligned, unless left is set.
+type alignedTable struct {
+	w      io.Writer
+	header []string
+	rows   [][]string
+	left   bool
+}
+
+func (t *alignedTable) Append(row []string) {
+	t.rows = append(t.rows, row)
+}
+
+func (t *alignedTable) Render() {
+	rows := t.rows
+	if t.header != nil {
+		rows = append([][]string{t.header}, rows...)
+	}
+
+	widths := make([]int, 0)
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if width := runewidth.StringWidth(cell); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	for _, row := range rows {
+		var b strings.Builder
+		for i, cell := range row {
+			padding := strings.Repeat(" ", widths[i]-runewidth.StringWidth(cell))
+			switch {
+			case i == 0 && len(row) == 1:
+				b.WriteString(cell)
+			case i == 0:
+				b.WriteString(cell + padding)
+			case t.left && i == len(row)-1:
+				b.WriteString("  " + cell)
+			case t.left:
+				b.WriteString("  " + cell + padding)
+			default:
+				b.WriteString("  " + padding + cell)
+			}
+		}
+		fmt.Fprintln(t.w, b.String())
+	}
+}
+
+// alignCounts right-aligns every column after the first. tablewriter only
+// right-aligns cells it recognises as numbers, which formatted counts are not.
+// Aligned tables always right-align counts.
+func alignCounts(rows rowTable, columns int) {
+	table, ok := rows.(*tablewriter.Table)
+	if !ok {
+		return
+	}
+
+	alignment := make([]int, columns)
+	alignment[0] = tablewriter.ALIGN_LEFT
+	for i := 1; i < columns; i++ {
+		alignment[i] = tablewriter.ALIGN_RIGHT
+	}
+	table.SetColumnAlignment(alignment)
+}
+
+// formatThousands inserts a comma between every group of three digits.
+func formatThousands(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String()
+}
+
+// siUnits are the suffixes of formatSI, smallest first.
+var siUnits = []struct {
+	scale  float64
+	suffix string
+}{{1e3, "k"}, {1e6, "M"}, {1e9, "G"}}
+
+// formatSI abbreviates n with a k, M or G suffix and one decimal place. The
+// suffix is chosen after rounding, so 999999 is 1.0M rather than 1000.0k.
+func formatSI(n int) string {
+	value := float64(n)
+	if math.Abs(value) < siUnits[0].scale {
+		return strconv.Itoa(n)
+	}
+	for i, unit := range siUnits {
+		scaled := math.Round(value/unit.scale*10) / 10
+		if math.Abs(scaled) < 1000 || i == len(siUnits)-1 {
+			return strconv.FormatFloat(scaled, 'f', 1, 64) + unit.suffix
+		}
+	}
+	return strconv.Itoa(n)
+}
+
+// outputFormats are the formats writeOutput renders.
+var outputFormats = []string{"table", "table-wide", "json", "grafana-json", "tree-json", "influx", "prometheus", "openmetrics", "line", "ndjson", "env", "nested-text"}
+
+// checkOutputFormat rejects a format writeOutput cannot render, so a typo
+// fails before the scan rather than after it.
+func checkOutputFormat(format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	return nil
+}
+
+// writeOutput renders the report to w in the requested format.
+func writeOutput(w io.Writer, format string, opts RenderOptions, report Report) error {
+	// Highlight the JSON formats on a terminal if specified. ndjson is left
+	// plain since buffering it for highlighting would stop it streaming
+	if opts.Pretty && opts.Color && strings.HasSuffix(format, "json") && format != "ndjson" {
+		plain := opts
+		plain.Pretty = false
+
+		var buf bytes.Buffer
+		if err := writeOutput(&buf, format, plain, report); err != nil {
+			return err
+		}
+		_, err := w.Write(highlightJSON(buf.Bytes()))
+		return err
+	}
+
+	switch format {
+	case "table":
+		printTables(w, opts, report)
+		return nil
+	case "table-wide":
+		opts.Aligned = true
+		printTables(w, opts, report)
+		return nil
+	case "json":
+		if opts.KeyByID {
+			return writeKeyedJSON(w, report)
+		}
+		return writeJSON(w, report)
+	case "grafana-json":
+		return writeGrafanaJSON(w, report)
+	case "tree-json":
+		return writeTreeJSON(w, report)
+	case "influx":
+		return writeInflux(w, opts, report)
+	case "prometheus":
+		return writePrometheus(w, report, false)
+	case "openmetrics":
+		return writePrometheus(w, report, true)
+	case "line":
+		return writeLines(w, report)
+	case "ndjson":
+		return writeNDJSON(w, report)
+	case "env":
+		return writeEnv(w, report)
+	case "nested-text":
+		return writeNestedText(w, opts, report)
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// writeOutputFile renders the report into the file at path, without
+// terminal colors or fitting to the terminal width. With compress the file
+// is gzipped and gets a .gz suffix.
+func writeOutputFile(path string, format string, opts RenderOptions, report Report, compress bool) error {
+	opts.Color = false
+	opts.TermWidth = 0
+
+	if compress && !strings.HasSuffix(path, ".gz") {
+		path += ".gz"
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if !compress {
+		if err := writeOutput(file, format, opts, report); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+
+	// The gzip footer is only written by Close, before the file is closed
+	gz := gzip.NewWriter(file)
+	if err := writeOutput(gz, format, opts, report); err != nil {
+		gz.Close()
+		file.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeSplitFiles writes one JSON file per workspace into dir, named after
+// the workspace, so downstream jobs can process workspaces independently.
+func writeSplitFiles(dir string, report Report) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	workspaces := report.Workspaces
+	if report.Default != nil {
+		workspaces = append([]WorkspaceMetadata{*report.Default}, workspaces...)
+	}
+	for _, metadata := range workspaces {
+		data, err := json.MarshalIndent(metadata, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, filepath.Base(metadata.WorkspaceName)+".json")
+		if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HistoryEntry is one line of the --append-file history: the report and
+// when it was collected.
+type HistoryEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Report
+}
+
+// appendHistory appends the report as a single JSON line to the file at
+// path, creating it if needed, so repeated runs build up a time series.
+func appendHistory(path string, report Report) error {
+	line, err := json.Marshal(HistoryEntry{Timestamp: time.Now().UTC(), Report: report})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func printTables(w io.Writer, opts RenderOptions, report Report) {
+	// Say which cluster the tables describe if known
+	if report.Cluster != nil {
+		printClusterIdentity(w, report.Cluster)
+	}
+
+	// Print individual workspace metadata if specified
+	if opts.Meta == "workspace" || opts.Meta == "all" {
+		if !opts.Quiet {
+			fmt.Fprintln(w, "Individual Workspace Metadata:")
+		}
+		printWorkspaceMetadataTable(w, report.Workspaces, opts)
+	}
+
+	// Print total counts if specified
+	if opts.Meta == "counts" || opts.Meta == "all" {
+		if !opts.Quiet {
+			fmt.Fprintln(w, "Total Meta Field Counts:")
+		}
+		printCountsTable(w, report.Totals, len(report.Workspaces), opts)
+	}
+
+	// Print the default workspace on its own if it was set aside
+	if report.Default != nil {
+		if !opts.Quiet {
+			fmt.Fprintln(w, "Default Workspace Metadata:")
+		}
+		printWorkspaceMetadataTable(w, []WorkspaceMetadata{*report.Default}, opts)
+	}
+}
+
+func writeJSON(w io.Writer, report Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// KeyedReport is the json output with --key-by-id: the workspaces keyed by
+// their id, which survives renames, each with its name as a field.
+type KeyedReport struct {
+	Workspaces map[string]WorkspaceMetadata `json:"workspaces"`
+	Totals     map[string]int               `json:"totals"`
+	Default    *WorkspaceMetadata           `json:"default,omitempty"`
+	Cluster    *ClusterIdentity             `json:"cluster,omitempty"`
+}
+
+// report converts the keyed workspaces back into the list of a Report,
+// ordered by name. A key other than the name is the workspace id.
+func (keyed KeyedReport) report() Report {
+	report := Report{
+		Workspaces: make([]WorkspaceMetadata, 0, len(keyed.Workspaces)),
+		Totals:     keyed.Totals,
+		Default:    keyed.Default,
+		Cluster:    keyed.Cluster,
+	}
+	for key, metadata := range keyed.Workspaces {
+		if key != metadata.WorkspaceName {
+			metadata.ID = key
+		}
+		report.Workspaces = append(report.Workspaces, metadata)
+	}
+	sort.Slice(report.Workspaces, func(i, j int) bool {
+		return report.Workspaces[i].WorkspaceName < report.Workspaces[j].WorkspaceName
+	})
+	return report
+}
+
+// writeKeyedJSON emits the report with workspaces keyed by id. Entries
+// without an id, such as those from a declarative config or --merge-group,
+// are keyed by name.
+func writeKeyedJSON(w io.Writer, report Report) error {
+	keyed := KeyedReport{
+		Workspaces: make(map[string]WorkspaceMetadata, len(report.Workspaces)),
+		Totals:     report.Totals,
+		Default:    report.Default,
+		Cluster:    report.Cluster,
+	}
+	for _, metadata := range report.Workspaces {
+		key := metadata.ID
+		if key == "" {
+			key = metadata.WorkspaceName
+		}
+		keyed.Workspaces[key] = metadata
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(keyed)
+}
+
+// writeRaw emits the raw /meta response bodies as one JSON object keyed by
+// workspace name. Bodies that are not valid JSON are embedded as strings.
+func writeRaw(w io.Writer, metadataList []WorkspaceMetadata) error {
+	raw := make(map[string]interface{}, len(metadataList))
+	for _, metadata := range metadataList {
+		if json.Valid(metadata.Raw) {
+			raw[metadata.WorkspaceName] = json.RawMessage(metadata.Raw)
+		} else {
+			raw[metadata.WorkspaceName] = string(metadata.Raw)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(raw)
+}
+
+// writeLines emits one grep-friendly line per workspace with every entity
+// count as a key=value pair, e.g. "team-a plugins=5 routes=34 services=12".
+func writeLines(w io.Writer, report Report) error {
+	workspaces := report.Workspaces
+	if report.Default != nil {
+		workspaces = append([]WorkspaceMetadata{*report.Default}, workspaces...)
+	}
+	for _, metadata := range workspaces {
+		fields := make([]string, 0, len(metadata.Meta.Counts)+1)
+		fields = append(fields, metadata.WorkspaceName)
+		for _, field := range sortedKeys(metadata.Meta.Counts) {
+			fields = append(fields, field+"="+strconv.Itoa(metadata.Meta.Counts[field]))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(fields, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeNDJSON streams one JSON object per workspace, one per line, with a
+// write per workspace so a slow consumer applies backpressure instead of the
+// whole output being held in memory.
+func writeNDJSON(w io.Writer, report Report) error {
+	workspaces := report.Workspaces
+	if report.Default != nil {
+		workspaces = append([]WorkspaceMetadata{*report.Default}, workspaces...)
+	}
+
+	// Encode writes each value with a single call to w
+	encoder := json.NewEncoder(w)
+	for _, metadata := range workspaces {
+		if err := encoder.Encode(metadata); err != nil {
+			return err
+		}
+		if flusher, ok := w.(interface{ Flush() error }); ok {
+			if err := flusher.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// envNameInvalid matches the characters not allowed in a shell variable name.
+var envNameInvalid = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// writeEnv emits the totals as shell variable assignments, e.g.
+// "KONG_TOTAL_SERVICES=123", for eval or sourcing in scripts.
+func writeEnv(w io.Writer, report Report) error {
+	if _, err := fmt.Fprintf(w, "KONG_TOTAL_WORKSPACES=%d\n", len(report.Workspaces)); err != nil {
+		return err
+	}
+	for _, field := range sortedKeys(report.Totals) {
+		name := "KONG_TOTAL_" + envNameInvalid.ReplaceAllString(strings.ToUpper(field), "_")
+		if _, err := fmt.Fprintf(w, "%s=%d\n", name, report.Totals[field]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GrafanaMetric is a single sample in the grafana-json output.
+type GrafanaMetric struct {
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels"`
+	Value  int               `json:"value"`
+}
+
+// writeGrafanaJSON emits per-workspace and aggregated counts as a flat array
+// of metric samples for the Grafana JSON datasource.
+func writeGrafanaJSON(w io.Writer, report Report) error {
+	metrics := make([]GrafanaMetric, 0)
+
+	workspaces := report.Workspaces
+	if report.Default != nil {
+		workspaces = append([]WorkspaceMetadata{*report.Default}, workspaces...)
+	}
+	for _, metadata := range workspaces {
+		for _, field := range sortedKeys(metadata.Meta.Counts) {
+			metrics = append(metrics, GrafanaMetric{
+				Metric: "kong_workspace_entities",
+				Labels: map[string]string{"workspace": metadata.WorkspaceName, "entity": field},
+				Value:  metadata.Meta.Counts[field],
+			})
+		}
+	}
+
+	for _, field := range sortedKeys(report.Totals) {
+		metrics = append(metrics, GrafanaMetric{
+			Metric: "kong_entities_total",
+			Labels: map[string]string{"entity": field},
+			Value:  report.Totals[field],
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(metrics)
+}
+
+// TreeReport is the tree-json output: the totals alongside a map of each
+// workspace's counts keyed by workspace name.
+type TreeReport struct {
+	Totals     map[string]int            `json:"totals"`
+	Workspaces map[string]map[string]int `json:"workspaces"`
+	Default    map[string]int            `json:"default,omitempty"`
+}
+
+// writeTreeJSON emits the report as a single nested object for frontends
+// that look workspaces up by name.
+func writeTreeJSON(w io.Writer, report Report) error {
+	tree := TreeReport{
+		Totals:     report.Totals,
+		Workspaces: workspaceCounts(report),
+	}
+	if report.Default != nil {
+		tree.Default = report.Default.Meta.Counts
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tree)
+}
+
+// sortedKeys returns the keys of counts in lexical order.
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
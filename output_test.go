@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestFormatThousands(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "0"},
+		{7, "7"},
+		{999, "999"},
+		{1000, "1,000"},
+		{12345, "12,345"},
+		{999999, "999,999"},
+		{1000000, "1,000,000"},
+		{1234567890, "1,234,567,890"},
+		{-1, "-1"},
+		{-999, "-999"},
+		{-1000, "-1,000"},
+		{-1234567, "-1,234,567"},
+	}
+	for _, tt := range tests {
+		if got := formatThousands(tt.n); got != tt.want {
+			t.Errorf("formatThousands(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSI(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1.0k"},
+		{1049, "1.0k"},
+		{1050, "1.1k"},
+		{999949, "999.9k"},
+		{999950, "1.0M"},
+		{999999, "1.0M"},
+		{1000000, "1.0M"},
+		{1234567, "1.2M"},
+		{999999999, "1.0G"},
+		{1500000000, "1.5G"},
+		{2000000000000, "2000.0G"},
+		{-999, "-999"},
+		{-1000, "-1.0k"},
+		{-999999, "-1.0M"},
+		{-1234567, "-1.2M"},
+	}
+	for _, tt := range tests {
+		if got := formatSI(tt.n); got != tt.want {
+			t.Errorf("formatSI(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
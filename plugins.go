@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// PluginBreakdown counts plugin instances by name, per workspace and in total.
+type PluginBreakdown struct {
+	Workspaces map[string]map[string]int `json:"workspaces"`
+	Totals     map[string]int            `json:"totals"`
+	// Enabled counts only the enabled instances, when requested
+	Enabled map[string]int `json:"enabled,omitempty"`
+}
+
+// collectPluginBreakdown lists the plugins of every workspace and counts them
+// by plugin name. With countEnabled the enabled instances are also counted
+// separately, since the meta count includes disabled plugins. Up to
+// concurrency workspaces are listed in parallel.
+func collectPluginBreakdown(ctx context.Context, client *Client, baseURL string, workspaces []Workspace, countEnabled bool, concurrency int) (PluginBreakdown, error) {
+	breakdown := PluginBreakdown{
+		Workspaces: make(map[string]map[string]int),
+		Totals:     make(map[string]int),
+	}
+	if countEnabled {
+		breakdown.Enabled = make(map[string]int)
+	}
+
+	listings, err := listWorkspacePlugins(ctx, client, baseURL, workspaces, concurrency)
+	if err != nil {
+		return PluginBreakdown{}, err
+	}
+
+	for index, plugins := range listings {
+		workspace := workspaces[index]
+		counts := make(map[string]int)
+		for _, plugin := range plugins {
+			counts[stringField(plugin, "name")]++
+			if countEnabled && boolField(plugin, "enabled") {
+				breakdown.Enabled[stringField(plugin, "name")]++
+			}
+		}
+		breakdown.Workspaces[workspace.Name] = counts
+		updateCounts(counts, breakdown.Totals)
+	}
+	return breakdown, nil
+}
+
+// listWorkspacePlugins lists the plugins of every workspace, up to
+// concurrency workspaces in parallel. The listings are returned in the order
+// of workspaces.
+func listWorkspacePlugins(ctx context.Context, client *Client, baseURL string, workspaces []Workspace, concurrency int) ([][]map[string]any, error) {
+	listings := make([][]map[string]any, len(workspaces))
+	err := forEachLimit(ctx, len(workspaces), concurrency, func(ctx context.Context, index int) error {
+		plugins, err := listEntities(ctx, client, baseURL, workspaces[index].Name, "plugins")
+		if err != nil {
+			return fmt.Errorf("listing plugins in workspace %s: %w", workspaces[index].Name, err)
+		}
+		listings[index] = plugins
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return listings, nil
+}
+
+// writePluginBreakdown renders the plugin counts as a table, or as JSON when
+// format is json.
+func writePluginBreakdown(w io.Writer, format string, quiet bool, breakdown PluginBreakdown) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(breakdown)
+	}
+
+	// Count how many workspaces use each plugin
+	usage := make(map[string]int)
+	for _, counts := range breakdown.Workspaces {
+		for name := range counts {
+			usage[name]++
+		}
+	}
+
+	// Order plugins by total count, highest first
+	names := sortedKeys(breakdown.Totals)
+	sort.SliceStable(names, func(i, j int) bool {
+		return breakdown.Totals[names[i]] > breakdown.Totals[names[j]]
+	})
+
+	if !quiet {
+		fmt.Fprintln(w, "Plugin Breakdown:")
+	}
+	table := tablewriter.NewWriter(w)
+	if breakdown.Enabled != nil {
+		table.SetHeader([]string{"Plugin", "Workspaces", "Count", "Enabled"})
+	} else {
+		table.SetHeader([]string{"Plugin", "Workspaces", "Count"})
+	}
+	for _, name := range names {
+		row := []string{name, strconv.Itoa(usage[name]), strconv.Itoa(breakdown.Totals[name])}
+		if breakdown.Enabled != nil {
+			row = append(row, strconv.Itoa(breakdown.Enabled[name]))
+		}
+		table.Append(row)
+	}
+	table.Render()
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+)
+
+// ANSI colors of JSON keys and numbers in --pretty output.
+const (
+	jsonKeyColor    = "36"
+	jsonNumberColor = "33"
+)
+
+// highlightJSON colors the keys and numbers of encoded JSON for reading on a
+// terminal. Everything else, including string values, is left as is.
+func highlightJSON(data []byte) []byte {
+	var out bytes.Buffer
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '"':
+			// Find the closing quote, skipping escaped characters
+			end := i + 1
+			for end < len(data) && data[end] != '"' {
+				if data[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(data) {
+				end++
+			}
+			token := string(data[i:end])
+
+			// A string followed by a colon is an object key
+			next := end
+			for next < len(data) && strings.IndexByte(" \t\r\n", data[next]) >= 0 {
+				next++
+			}
+			if next < len(data) && data[next] == ':' {
+				out.WriteString(colorize(token, jsonKeyColor))
+			} else {
+				out.WriteString(token)
+			}
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i
+			for end < len(data) && strings.IndexByte("+-0123456789.eE", data[end]) >= 0 {
+				end++
+			}
+			out.WriteString(colorize(string(data[i:end]), jsonNumberColor))
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is a set of connection settings from the --config file.
+type Profile struct {
+	KongAddr string        `yaml:"kong_addr"`
+	Token    string        `yaml:"token"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// ConfigFile is the document read by --config. The top-level settings apply
+// to every profile unless the profile overrides them.
+type ConfigFile struct {
+	Profile  `yaml:",inline"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// loadProfile reads the config file at path and returns its settings,
+// merged with the named profile when name is not empty.
+func loadProfile(path string, name string) (Profile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	var file ConfigFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return Profile{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if name == "" {
+		return file.Profile, nil
+	}
+
+	profile, ok := file.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(file.Profiles))
+		for profileName := range file.Profiles {
+			names = append(names, profileName)
+		}
+		sort.Strings(names)
+		return Profile{}, fmt.Errorf("profile %q not found in %s (available: %s)", name, path, strings.Join(names, ", "))
+	}
+
+	merged := file.Profile
+	if profile.KongAddr != "" {
+		merged.KongAddr = profile.KongAddr
+	}
+	if profile.Token != "" {
+		merged.Token = profile.Token
+	}
+	if profile.Timeout != 0 {
+		merged.Timeout = profile.Timeout
+	}
+	return merged, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promLabelEscaper escapes label values in the Prometheus and OpenMetrics
+// text formats.
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writePrometheus emits the counts as gauges in the Prometheus text format,
+// or with openMetrics in the OpenMetrics format, which adds a sample
+// timestamp to every line and ends with # EOF.
+func writePrometheus(w io.Writer, report Report, openMetrics bool) error {
+	timestamp := ""
+	if openMetrics {
+		timestamp = " " + strconv.FormatFloat(float64(time.Now().UnixMilli())/1000, 'f', 3, 64)
+	}
+
+	var b strings.Builder
+	b.WriteString("# HELP kong_workspace_entities Number of entities of a type in a workspace.\n")
+	b.WriteString("# TYPE kong_workspace_entities gauge\n")
+	workspaces := report.Workspaces
+	if report.Default != nil {
+		workspaces = append([]WorkspaceMetadata{*report.Default}, workspaces...)
+	}
+	for _, metadata := range workspaces {
+		for _, field := range sortedKeys(metadata.Meta.Counts) {
+			fmt.Fprintf(&b, "kong_workspace_entities{workspace=\"%s\",entity=\"%s\"} %d%s\n",
+				promLabelEscaper.Replace(metadata.WorkspaceName), promLabelEscaper.Replace(field), metadata.Meta.Counts[field], timestamp)
+		}
+	}
+
+	b.WriteString("# HELP kong_cluster_entities Number of entities of a type across the reported workspaces.\n")
+	b.WriteString("# TYPE kong_cluster_entities gauge\n")
+	for _, field := range sortedKeys(report.Totals) {
+		fmt.Fprintf(&b, "kong_cluster_entities{entity=\"%s\"} %d%s\n", promLabelEscaper.Replace(field), report.Totals[field], timestamp)
+	}
+
+	if openMetrics {
+		b.WriteString("# EOF\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
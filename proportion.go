@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// proportionBarWidth is the number of cells in the stacked share bar.
+const proportionBarWidth = 60
+
+// proportionColors are the ANSI foreground colors cycled through for the
+// segments of the share bar.
+var proportionColors = []string{"31", "32", "33", "34", "35", "36", "91", "92", "93", "94", "95", "96"}
+
+// colorSupported reports whether colored output should be written to file:
+// NO_COLOR must be unset and the file must be a terminal.
+func colorSupported(file *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// printProportionBar renders every field's share of the total count as one
+// stacked bar with a legend. Without color the segments cannot be told
+// apart, so only the labeled percentages are printed.
+func printProportionBar(w io.Writer, counts map[string]int, color bool) {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return
+	}
+
+	// Largest share first, ties by name so the bar is stable
+	fields := sortedKeys(counts)
+	sort.SliceStable(fields, func(i, j int) bool {
+		return counts[fields[i]] > counts[fields[j]]
+	})
+
+	legend := make([]string, 0, len(fields))
+	for _, field := range fields {
+		legend = append(legend, fmt.Sprintf("%s %.1f%%", field, float64(counts[field])*100/float64(total)))
+	}
+
+	if !color {
+		fmt.Fprintln(w, strings.Join(legend, " | "))
+		return
+	}
+
+	// Segment ends are rounded from the running total so the bar always
+	// spans exactly proportionBarWidth cells
+	var bar strings.Builder
+	cumulative, drawn := 0, 0
+	for i, field := range fields {
+		cumulative += counts[field]
+		end := int(math.Round(float64(cumulative) * proportionBarWidth / float64(total)))
+		if end > drawn {
+			bar.WriteString(colorize(strings.Repeat("█", end-drawn), proportionColors[i%len(proportionColors)]))
+			drawn = end
+		}
+	}
+	fmt.Fprintln(w, bar.String())
+
+	for i, entry := range legend {
+		fmt.Fprintf(w, "%s %s\n", colorize("■", proportionColors[i%len(proportionColors)]), entry)
+	}
+}
+
+// colorize wraps s in the given ANSI color code.
+func colorize(s string, code string) string {
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
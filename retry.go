@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryBaseDelay is the wait before the first retry; it doubles with each
+// further attempt.
+const retryBaseDelay = 500 * time.Millisecond
+
+// parseRetryStatus parses a comma-separated list of HTTP status codes.
+func parseRetryStatus(list string) (map[int]bool, error) {
+	statuses := make(map[int]bool)
+	if list == "" {
+		return statuses, nil
+	}
+
+	for _, field := range strings.Split(list, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status code %q", field)
+		}
+		statuses[code] = true
+	}
+	return statuses, nil
+}
+
+// retryable reports whether a response with this status should be retried.
+func (c *Client) retryable(status int) bool {
+	return status >= 500 || c.RetryStatus[status]
+}
+
+// retryDelay returns how long to wait before retrying resp. The server's
+// Retry-After header, in seconds or as an HTTP date, takes precedence over
+// the exponential schedule.
+func retryDelay(attempt int, resp *http.Response) time.Duration {
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(retryAfter); err == nil {
+			if delay := time.Until(at); delay > 0 {
+				return delay
+			}
+			return 0
+		}
+	}
+	return retryBaseDelay << attempt
+}
+
+// sleepContext waits for d, returning early with the context's error if ctx
+// is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
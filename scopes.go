@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// pluginScopes are the scopes a plugin can be attached at, from the least to
+// the most specific.
+var pluginScopes = []string{"global", "service", "route", "consumer"}
+
+// pluginScope classifies a listed plugin by the most specific foreign key it
+// is attached to, following Kong's precedence of consumer over route over
+// service. A plugin with none of them set is global.
+func pluginScope(plugin map[string]any) string {
+	for i := len(pluginScopes) - 1; i > 0; i-- {
+		if plugin[pluginScopes[i]] != nil {
+			return pluginScopes[i]
+		}
+	}
+	return "global"
+}
+
+// collectPluginScopes lists the plugins of every workspace and counts them
+// by scope. Up to concurrency workspaces are listed in parallel.
+func collectPluginScopes(ctx context.Context, client *Client, baseURL string, workspaces []Workspace, concurrency int) (map[string]map[string]int, error) {
+	listings, err := listWorkspacePlugins(ctx, client, baseURL, workspaces, concurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make(map[string]map[string]int, len(workspaces))
+	for index, plugins := range listings {
+		workspace := workspaces[index]
+		counts := make(map[string]int, len(pluginScopes))
+		for _, plugin := range plugins {
+			counts[pluginScope(plugin)]++
+		}
+		scopes[workspace.Name] = counts
+	}
+	return scopes, nil
+}
+
+// writePluginScopes renders the per-workspace scope counts as a table with a
+// "(total)" row, or as JSON when format is json.
+func writePluginScopes(w io.Writer, format string, quiet bool, scopes map[string]map[string]int) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(scopes)
+	}
+
+	names := make([]string, 0, len(scopes))
+	for name := range scopes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+
+	if !quiet {
+		fmt.Fprintln(w, "Plugins By Scope:")
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(append([]string{"Workspace Name"}, columnTitles(pluginScopes)...))
+	totals := make(map[string]int, len(pluginScopes))
+	for _, name := range names {
+		row := []string{name}
+		for _, scope := range pluginScopes {
+			row = append(row, strconv.Itoa(scopes[name][scope]))
+			totals[scope] += scopes[name][scope]
+		}
+		table.Append(row)
+	}
+	row := []string{"(total)"}
+	for _, scope := range pluginScopes {
+		row = append(row, strconv.Itoa(totals[scope]))
+	}
+	table.Append(row)
+	table.Render()
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// metricsHandler serves the counts in the Prometheus text format, collecting
+// from Kong on a scrape unless the last collection is more recent than
+// minInterval.
+type metricsHandler struct {
+	collect     func(context.Context) (Report, error)
+	minInterval time.Duration
+
+	mu        sync.Mutex
+	body      []byte
+	collected time.Time
+}
+
+func (h *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.body == nil || time.Since(h.collected) >= h.minInterval {
+		report, err := h.collect(r.Context())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error collecting metadata:", err)
+			http.Error(w, "collecting metadata: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := writePrometheus(&buf, report, false); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.body, h.collected = buf.Bytes(), time.Now()
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(h.body)
+}
+
+// serveMetrics exposes handler at /metrics on addr until ctx is done.
+func serveMetrics(ctx context.Context, addr string, handler http.Handler) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
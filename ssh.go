@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshJumpTimeout bounds connecting to and authenticating with the bastion.
+const sshJumpTimeout = 15 * time.Second
+
+// sshKeyFiles are the private keys tried, in order, when present in ~/.ssh.
+var sshKeyFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// dialSSHJump connects to the bastion given as "user@host[:port]" and returns
+// the SSH client whose DialContext opens connections from the bastion. It
+// authenticates with the SSH agent and the unencrypted keys in ~/.ssh, and
+// only trusts host keys listed in ~/.ssh/known_hosts, like ssh does.
+func dialSSHJump(target string) (*ssh.Client, error) {
+	login, host, found := strings.Cut(target, "@")
+	if !found {
+		current, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("no user in %q and the current user is unknown: %w", target, err)
+		}
+		login, host = current.Username, target
+	}
+	if login == "" || host == "" {
+		return nil, fmt.Errorf("%q is not in the form user@host[:port]", target)
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostKeys, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("reading known hosts: %w", err)
+	}
+	auth, closeAgent, err := sshAuthMethods(home)
+	if err != nil {
+		return nil, err
+	}
+	// The agent is only needed to authenticate, which ssh.Dial completes
+	defer closeAgent()
+
+	config := &ssh.ClientConfig{
+		User:            login,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         sshJumpTimeout,
+	}
+	client, err := ssh.Dial("tcp", host, config)
+	var keyErr *knownhosts.KeyError
+	if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+		return nil, fmt.Errorf("host key of %s is not in ~/.ssh/known_hosts; connect once with ssh to add it", host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s as %s: %w", host, login, err)
+	}
+	return client, nil
+}
+
+// sshAuthMethods collects the running SSH agent and any unencrypted default
+// keys. Keys protected by a passphrase are skipped; load them into the agent.
+// The returned function closes the agent connection and must be called once
+// authentication is done.
+func sshAuthMethods(home string) ([]ssh.AuthMethod, func(), error) {
+	var methods []ssh.AuthMethod
+	closeAgent := func() {}
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			closeAgent = func() { conn.Close() }
+		}
+	}
+
+	var signers []ssh.Signer
+	for _, name := range sshKeyFiles {
+		pem, err := os.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			continue
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+
+	if len(methods) == 0 {
+		return nil, nil, errors.New("no SSH agent running and no unencrypted key in ~/.ssh")
+	}
+	return methods, closeAgent, nil
+}
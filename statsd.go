@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// statsdMaxPacket keeps each datagram within a typical network MTU.
+const statsdMaxPacket = 1432
+
+// statsdSanitizer replaces the characters that delimit StatsD metric names,
+// values and tags.
+var statsdSanitizer = strings.NewReplacer(":", "_", "|", "_", "@", "_", ",", "_", "#", "_", "\n", "_")
+
+// pushStatsd sends every workspace count as a gauge named prefix.entity,
+// tagged with the workspace in the DogStatsD format, to the agent at addr
+// over UDP. Several gauges are batched per datagram.
+func pushStatsd(addr string, prefix string, tags []string, report Report) error {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var packet bytes.Buffer
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+
+	workspaces := report.Workspaces
+	if report.Default != nil {
+		workspaces = append([]WorkspaceMetadata{*report.Default}, workspaces...)
+	}
+	staticTags := ""
+	if len(tags) > 0 {
+		staticTags = strings.Join(tags, ",") + ","
+	}
+	for _, metadata := range workspaces {
+		gaugeTags := staticTags + "workspace:" + statsdSanitizer.Replace(metadata.WorkspaceName)
+		for _, field := range sortedKeys(metadata.Meta.Counts) {
+			gauge := fmt.Sprintf("%s.%s:%d|g|#%s", prefix, statsdSanitizer.Replace(field), metadata.Meta.Counts[field], gaugeTags)
+
+			if packet.Len() > 0 && packet.Len()+1+len(gauge) > statsdMaxPacket {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			if packet.Len() > 0 {
+				packet.WriteByte('\n')
+			}
+			packet.WriteString(gauge)
+		}
+	}
+	return flush()
+}
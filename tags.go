@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// taggedEntities are the entity endpoints queried by --by-tag.
+var taggedEntities = []string{"services", "routes", "plugins", "consumers", "upstreams", "certificates"}
+
+// untaggedLabel groups entities that carry no tags.
+const untaggedLabel = "(untagged)"
+
+// collectTagCounts lists the tagged entities of every workspace and counts
+// them per tag value and entity type. An entity with several tags is counted
+// once under each of them. Up to concurrency workspaces are listed in
+// parallel, each with up to detailConcurrency entity listings in flight.
+func collectTagCounts(ctx context.Context, client *Client, baseURL string, workspaces []Workspace, concurrency int, detailConcurrency int) (map[string]map[string]int, error) {
+	// Listings are stored by workspace and entity index, then counted in order
+	listings := make([][][]map[string]any, len(workspaces))
+	err := forEachLimit(ctx, len(workspaces), concurrency, func(ctx context.Context, w int) error {
+		listings[w] = make([][]map[string]any, len(taggedEntities))
+		return forEachLimit(ctx, len(taggedEntities), detailConcurrency, func(ctx context.Context, e int) error {
+			items, err := listEntities(ctx, client, baseURL, workspaces[w].Name, taggedEntities[e])
+			if err != nil {
+				return fmt.Errorf("listing %s in workspace %s: %w", taggedEntities[e], workspaces[w].Name, err)
+			}
+			listings[w][e] = items
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tagCounts := make(map[string]map[string]int)
+	for _, entityListings := range listings {
+		for e, items := range entityListings {
+			entity := taggedEntities[e]
+			for _, item := range items {
+				tags := stringsField(item, "tags")
+				if len(tags) == 0 {
+					tags = []string{untaggedLabel}
+				}
+				for _, tag := range tags {
+					if tagCounts[tag] == nil {
+						tagCounts[tag] = make(map[string]int)
+					}
+					tagCounts[tag][entity]++
+				}
+			}
+		}
+	}
+	return tagCounts, nil
+}
+
+// writeTagCounts renders the per-tag counts as a table, or as JSON when
+// format is json.
+func writeTagCounts(w io.Writer, format string, quiet bool, tagCounts map[string]map[string]int) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(tagCounts)
+	}
+
+	tags := make([]string, 0, len(tagCounts))
+	for tag := range tagCounts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	if !quiet {
+		fmt.Fprintln(w, "Entity Counts By Tag:")
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(append(append([]string{"Tag"}, taggedEntities...), "Total"))
+	for _, tag := range tags {
+		row := []string{tag}
+		total := 0
+		for _, entity := range taggedEntities {
+			row = append(row, strconv.Itoa(tagCounts[tag][entity]))
+			total += tagCounts[tag][entity]
+		}
+		table.Append(append(row, strconv.Itoa(total)))
+	}
+	table.Render()
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io"
+	"text/template"
+)
+
+// templateFuncs are the helpers available to --format-template.
+var templateFuncs = template.FuncMap{
+	// field returns one entity count of a workspace, zero when absent
+	"field": func(metadata WorkspaceMetadata, name string) int {
+		return metadata.Meta.Counts[name]
+	},
+	// sum adds up every count in a counts map
+	"sum": func(counts map[string]int) int {
+		total := 0
+		for _, count := range counts {
+			total += count
+		}
+		return total
+	},
+	// keys returns the field names of a counts map in lexical order
+	"keys": sortedKeys,
+}
+
+// parseFormatTemplate compiles a --format-template string.
+func parseFormatTemplate(text string) (*template.Template, error) {
+	return template.New("format").Funcs(templateFuncs).Parse(text)
+}
+
+// writeTemplate executes tmpl against the report. Templates see the Report
+// fields .Workspaces (each with .WorkspaceName and .Meta.Counts), .Totals
+// and .Default.
+func writeTemplate(w io.Writer, tmpl *template.Template, report Report) error {
+	return tmpl.Execute(w, report)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestTiming is one admin API request recorded for --timings-file.
+type RequestTiming struct {
+	Workspace  string  `json:"workspace"`
+	URL        string  `json:"url"`
+	DurationMS float64 `json:"duration_ms"`
+	// Status is zero when the request failed without a response
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// timingTransport records the duration and outcome of every request,
+// retries included.
+type timingTransport struct {
+	base http.RoundTripper
+
+	mu      sync.Mutex
+	timings []RequestTiming
+}
+
+func (t *timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	timing := RequestTiming{
+		Workspace:  workspaceFromPath(req.URL.Path),
+		URL:        req.URL.Redacted(),
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		timing.Error = err.Error()
+	} else {
+		timing.Status = resp.StatusCode
+	}
+
+	t.mu.Lock()
+	t.timings = append(t.timings, timing)
+	t.mu.Unlock()
+	return resp, err
+}
+
+// writeTimings writes the recorded timings to path as a JSON array, in the
+// order the requests completed.
+func (t *timingTransport) writeTimings(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timings := t.timings
+	if timings == nil {
+		timings = []RequestTiming{}
+	}
+	data, err := json.MarshalIndent(timings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// workspaceFromPath returns the workspace a request path belongs to, e.g.
+// "team-a" for /workspaces/team-a/meta, or "" for cluster-wide requests.
+func workspaceFromPath(path string) string {
+	_, rest, ok := strings.Cut(path, "/workspaces/")
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, "/")
+	return name
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runTokenCommand runs command through the shell and returns its trimmed
+// standard output as the admin token, so credential helpers can supply it.
+// The command's standard error is passed through for its prompts and errors.
+func runTokenCommand(ctx context.Context, command string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", errors.New("command printed no token")
+	}
+	return token, nil
+}
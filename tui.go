@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// tuiListWidth is the width of the workspace list on the left.
+const tuiListWidth = 32
+
+// tuiBrowser is the state of the interactive workspace browser shown with
+// --tui.
+type tuiBrowser struct {
+	workspaces []WorkspaceMetadata
+	filtered   []WorkspaceMetadata
+	filter     []rune
+	cursor     int
+	offset     int
+}
+
+// runTUI lets the user browse the collected report interactively, filtering
+// workspaces by name and showing the counts of the selected one.
+func runTUI(report Report) error {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	if err := screen.Init(); err != nil {
+		return err
+	}
+	defer screen.Fini()
+
+	workspaces := report.Workspaces
+	if report.Default != nil {
+		workspaces = append([]WorkspaceMetadata{*report.Default}, workspaces...)
+	}
+	browser := &tuiBrowser{workspaces: workspaces}
+	browser.applyFilter()
+
+	for {
+		browser.draw(screen)
+
+		switch event := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			switch event.Key() {
+			case tcell.KeyEscape, tcell.KeyCtrlC:
+				return nil
+			case tcell.KeyUp:
+				if browser.cursor > 0 {
+					browser.cursor--
+				}
+			case tcell.KeyDown:
+				if browser.cursor < len(browser.filtered)-1 {
+					browser.cursor++
+				}
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				if len(browser.filter) > 0 {
+					browser.filter = browser.filter[:len(browser.filter)-1]
+					browser.applyFilter()
+				}
+			case tcell.KeyRune:
+				browser.filter = append(browser.filter, event.Rune())
+				browser.applyFilter()
+			}
+		}
+	}
+}
+
+// draw renders the filter line, the workspace list and the counts of the
+// selected workspace.
+func (b *tuiBrowser) draw(screen tcell.Screen) {
+	screen.Clear()
+	_, height := screen.Size()
+	rows := height - 2
+	if rows < 1 {
+		rows = 1
+	}
+	b.scroll(rows)
+
+	// Filter line
+	if len(b.filter) == 0 {
+		drawText(screen, 0, 0, tcell.StyleDefault.Dim(true), "/ type to filter workspaces, esc to quit")
+	} else {
+		drawText(screen, 0, 0, tcell.StyleDefault, "/ "+string(b.filter))
+	}
+
+	// Workspace list
+	if len(b.filtered) == 0 {
+		drawText(screen, 0, 2, tcell.StyleDefault.Dim(true), "no matching workspaces")
+	}
+	for i := b.offset; i < len(b.filtered) && i < b.offset+rows; i++ {
+		style := tcell.StyleDefault
+		if i == b.cursor {
+			style = style.Reverse(true).Bold(true)
+		}
+		drawText(screen, 0, 2+i-b.offset, style, runewidth.Truncate(b.filtered[i].WorkspaceName, tuiListWidth-1, "…"))
+	}
+
+	// Separator and counts panel
+	for y := 2; y < height; y++ {
+		screen.SetContent(tuiListWidth, y, '│', nil, tcell.StyleDefault)
+	}
+	if b.cursor < len(b.filtered) {
+		selected := b.filtered[b.cursor]
+		drawText(screen, tuiListWidth+2, 2, tcell.StyleDefault.Bold(true), selected.WorkspaceName)
+		for i, field := range sortedKeys(selected.Meta.Counts) {
+			drawText(screen, tuiListWidth+2, 3+i, tcell.StyleDefault, fmt.Sprintf("%-16s %8d", field, selected.Meta.Counts[field]))
+		}
+	}
+
+	screen.Show()
+}
+
+// applyFilter recomputes the visible workspaces from the filter text.
+func (b *tuiBrowser) applyFilter() {
+	query := strings.ToLower(string(b.filter))
+	filtered := make([]WorkspaceMetadata, 0, len(b.workspaces))
+	for _, workspace := range b.workspaces {
+		if strings.Contains(strings.ToLower(workspace.WorkspaceName), query) {
+			filtered = append(filtered, workspace)
+		}
+	}
+	b.filtered = filtered
+
+	if b.cursor >= len(b.filtered) {
+		b.cursor = len(b.filtered) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+}
+
+// scroll keeps the cursor inside the visible window of the list.
+func (b *tuiBrowser) scroll(rows int) {
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+rows {
+		b.offset = b.cursor - rows + 1
+	}
+}
+
+// drawText writes s starting at column x of row y.
+func drawText(screen tcell.Screen, x int, y int, style tcell.Style, s string) {
+	for _, r := range s {
+		screen.SetContent(x, y, r, nil, style)
+		x += runewidth.RuneWidth(r)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sensitiveHeaders are request headers whose values are never logged.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Kong-Admin-Token":    true,
+	"Cookie":              true,
+}
+
+// verboseTransport logs every request and its outcome to w, and with
+// headers also the request and response headers, in the style of curl -v.
+type verboseTransport struct {
+	base    http.RoundTripper
+	w       io.Writer
+	headers bool
+	// mu keeps the lines of concurrent requests from interleaving
+	mu sync.Mutex
+	// metaLatencies are the durations of the successful metadata requests
+	metaLatencies []time.Duration
+}
+
+func (t *verboseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Fprintf(t.w, "> %s %s\n", req.Method, req.URL.Redacted())
+	if t.headers {
+		writeHeaders(t.w, ">", req.Header, true)
+	}
+
+	if err != nil {
+		fmt.Fprintf(t.w, "< error after %s: %v\n", elapsed, err)
+		return nil, err
+	}
+
+	if resp.StatusCode < 400 && strings.HasSuffix(req.URL.Path, "/meta") {
+		t.metaLatencies = append(t.metaLatencies, time.Since(start))
+	}
+
+	fmt.Fprintf(t.w, "< %s %s (%s)\n", resp.Proto, resp.Status, elapsed)
+	if t.headers {
+		writeHeaders(t.w, "<", resp.Header, false)
+	}
+	return resp, nil
+}
+
+// writeLatencySummary prints the minimum, average, maximum and percentiles
+// of the metadata request latencies, if any were made.
+func (t *verboseTransport) writeLatencySummary() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	latencies := append([]time.Duration(nil), t.metaLatencies...)
+	if len(latencies) == 0 {
+		return
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	round := func(d time.Duration) time.Duration { return d.Round(100 * time.Microsecond) }
+	fmt.Fprintf(t.w, "Metadata latency over %d requests: min %s, avg %s, max %s, p50 %s, p95 %s, p99 %s\n",
+		len(latencies),
+		round(latencies[0]), round(total/time.Duration(len(latencies))), round(latencies[len(latencies)-1]),
+		round(percentile(latencies, 50)), round(percentile(latencies, 95)), round(percentile(latencies, 99)))
+}
+
+// percentile returns the nearest-rank percentile p of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// writeHeaders prints header sorted by name, one value per line, hiding the
+// values of sensitive headers if redact is set.
+func writeHeaders(w io.Writer, prefix string, header http.Header, redact bool) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range header[name] {
+			if redact && sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+				value = "[REDACTED]"
+			}
+			fmt.Fprintf(w, "%s %s: %s\n", prefix, name, strings.TrimSpace(value))
+		}
+	}
+}
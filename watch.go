@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Terminal control sequences used to redraw the watch view in place.
+const (
+	cursorHome = "\x1b[H"
+	eraseLine  = "\x1b[K"
+	eraseBelow = "\x1b[J"
+)
+
+// redrawFrame overwrites the screen with frame in a single write: every line
+// is drawn over the previous one and cleared to its end, and whatever the
+// previous frame left below is erased. Unlike clearing the screen first, the
+// terminal never shows an empty frame, so large tables do not flicker.
+func redrawFrame(w io.Writer, frame []byte) error {
+	var buf bytes.Buffer
+	buf.Grow(len(frame) + 64)
+	buf.WriteString(cursorHome)
+	for _, line := range bytes.SplitAfter(frame, []byte("\n")) {
+		if trimmed, ok := bytes.CutSuffix(line, []byte("\n")); ok {
+			buf.Write(trimmed)
+			buf.WriteString(eraseLine + "\n")
+		} else {
+			buf.Write(line)
+		}
+	}
+	buf.WriteString(eraseBelow)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// sparkBlocks are the bar heights of a sparkline, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// totalsRing keeps the cluster-wide totals of the last watch cycles.
+type totalsRing struct {
+	values []int
+	next   int
+	full   bool
+}
+
+func newTotalsRing(size int) *totalsRing {
+	return &totalsRing{values: make([]int, size)}
+}
+
+// add records a total, overwriting the oldest once the ring is full.
+func (r *totalsRing) add(total int) {
+	r.values[r.next] = total
+	r.next = (r.next + 1) % len(r.values)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// ordered returns the recorded totals, oldest first.
+func (r *totalsRing) ordered() []int {
+	if !r.full {
+		return append([]int(nil), r.values[:r.next]...)
+	}
+	return append(append([]int(nil), r.values[r.next:]...), r.values[:r.next]...)
+}
+
+// sparkline draws one block per value, scaled between the smallest and the
+// largest value. A flat series is drawn at the lowest height.
+func sparkline(values []int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	low, high := values[0], values[0]
+	for _, value := range values {
+		low, high = min(low, value), max(high, value)
+	}
+
+	line := make([]rune, len(values))
+	for i, value := range values {
+		level := 0
+		if high > low {
+			level = (value - low) * (len(sparkBlocks) - 1) / (high - low)
+		}
+		line[i] = sparkBlocks[level]
+	}
+	return string(line)
+}
+
+// runWatch collects a report every interval and renders it to w until ctx is
+// done. From the second cycle on, table counts show their change since the
+// previous cycle. With a positive trend, tables are preceded by a sparkline
+// of the cluster-wide total over that many recent cycles. A failed cycle is
+// reported and retried at the next tick.
+func runWatch(ctx context.Context, w io.Writer, interval time.Duration, format string, trend int, opts RenderOptions, collect func(context.Context) (Report, error)) error {
+	// Redraw in place when the table goes to a terminal
+	redraw := false
+	tables := format == "table" || format == "table-wide"
+	if file, ok := w.(*os.File); ok && tables {
+		redraw = term.IsTerminal(int(file.Fd()))
+	}
+
+	var totals *totalsRing
+	if trend > 0 && tables {
+		totals = newTotalsRing(trend)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := collect(ctx)
+
+		// A cycle cut short by an interrupt is incomplete, so drop it
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error collecting metadata:", err)
+		} else {
+			// Render off-screen so a redraw replaces the frame in one write
+			out := w
+			var frame bytes.Buffer
+			if redraw {
+				out = &frame
+			}
+			if !opts.Quiet && tables {
+				fmt.Fprintf(out, "Every %s, updated %s\n\n", interval, time.Now().Format("15:04:05"))
+			}
+			if totals != nil {
+				totals.add(sumCounts(report.Totals))
+				recent := totals.ordered()
+				cycles := "cycles"
+				if len(recent) == 1 {
+					cycles = "cycle"
+				}
+				fmt.Fprintf(out, "Total %s %s (last %d %s, min %s, max %s)\n\n",
+					opts.formatCount(recent[len(recent)-1]), sparkline(recent), len(recent), cycles,
+					opts.formatCount(slices.Min(recent)), opts.formatCount(slices.Max(recent)))
+			}
+			if err := writeOutput(out, format, opts, report); err != nil {
+				return err
+			}
+			if redraw {
+				if err := redrawFrame(w, frame.Bytes()); err != nil {
+					return err
+				}
+			}
+			opts.Previous = &report
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// formatChange renders a count followed by its change since the previous
+// watch cycle, e.g. "120 (+3)". Outside watch mode, and for unchanged counts,
+// it is the same as formatCount.
+func (opts RenderOptions) formatChange(n int, previous int) string {
+	if opts.Previous == nil || n == previous {
+		return opts.formatCount(n)
+	}
+
+	delta := n - previous
+	sign := "+"
+	if delta < 0 {
+		sign, delta = "-", -delta
+	}
+	return fmt.Sprintf("%s (%s%s)", opts.formatCount(n), sign, opts.formatCount(delta))
+}
+
+// previousCounts returns the counts of the named workspace in the previous
+// watch cycle, or nil if there was none or the workspace is new.
+func (opts RenderOptions) previousCounts(name string) map[string]int {
+	if opts.Previous == nil {
+		return nil
+	}
+	if opts.Previous.Default != nil && opts.Previous.Default.WorkspaceName == name {
+		return opts.Previous.Default.Meta.Counts
+	}
+	return workspaceCounts(*opts.Previous)[name]
+}
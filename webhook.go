@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookTimeout bounds the whole webhook call, so an unresponsive endpoint
+// cannot hang the run.
+const webhookTimeout = 30 * time.Second
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// postWebhook POSTs the report as JSON to webhookURL with the given extra
+// headers and returns the response status. Statuses outside 2xx are errors.
+func postWebhook(ctx context.Context, webhookURL string, headers http.Header, report Report) (string, error) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.Status, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return resp.Status, nil
+}
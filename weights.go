@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseWeights parses comma-separated entity=weight pairs, such as
+// "services=1,routes=0.5,plugins=2".
+func parseWeights(list string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	if list == "" {
+		return weights, nil
+	}
+
+	for _, pair := range strings.Split(list, ",") {
+		entity, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || entity == "" {
+			return nil, fmt.Errorf("invalid entity=weight pair %q", pair)
+		}
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight for %s: %q", entity, value)
+		}
+		weights[entity] = weight
+	}
+	return weights, nil
+}
+
+// weightedScore sums each count multiplied by the weight of its entity.
+// Entities without a weight do not contribute.
+func weightedScore(counts map[string]int, weights map[string]float64) float64 {
+	score := 0.0
+	for entity, weight := range weights {
+		score += float64(counts[entity]) * weight
+	}
+	return score
+}
+
+// formatScore renders a weighted score without trailing zeros.
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
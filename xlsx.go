@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/xuri/excelize/v2"
+)
+
+// writeXLSX writes the report to a spreadsheet at path with a Workspaces
+// sheet of per-workspace counts and a Totals sheet, each with a bold,
+// frozen header row.
+func writeXLSX(path string, report Report, excluded map[string]bool) error {
+	book := excelize.NewFile()
+	defer book.Close()
+
+	header, err := book.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	workspaces := report.Workspaces
+	if report.Default != nil {
+		workspaces = append(workspaces[:len(workspaces):len(workspaces)], *report.Default)
+	}
+	columns := metadataColumns(workspaces, excluded)
+	rows := [][]any{toRow(append([]string{"Workspace Name"}, columnTitles(columns)...))}
+	for _, metadata := range workspaces {
+		row := []any{metadata.WorkspaceName}
+		for _, column := range columns {
+			row = append(row, metadata.Meta.Counts[column])
+		}
+		rows = append(rows, row)
+	}
+	if err := writeSheet(book, "Workspaces", rows, header); err != nil {
+		return err
+	}
+
+	rows = [][]any{{"Meta Field", "Count"}, {"Workspaces", len(report.Workspaces)}}
+	for _, field := range sortedKeys(report.Totals) {
+		rows = append(rows, []any{field, report.Totals[field]})
+	}
+	if err := writeSheet(book, "Totals", rows, header); err != nil {
+		return err
+	}
+
+	// NewFile starts with a Sheet1 that is not needed
+	if err := book.DeleteSheet("Sheet1"); err != nil {
+		return err
+	}
+	return book.SaveAs(path)
+}
+
+// writeSheet adds a sheet holding rows, styling the first row as a header
+// and keeping it in view while scrolling.
+func writeSheet(book *excelize.File, name string, rows [][]any, header int) error {
+	if _, err := book.NewSheet(name); err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := book.SetSheetRow(name, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	last, err := excelize.CoordinatesToCellName(len(rows[0]), 1)
+	if err != nil {
+		return err
+	}
+	if err := book.SetCellStyle(name, "A1", last, header); err != nil {
+		return err
+	}
+	if err := book.SetColWidth(name, "A", "A", 24); err != nil {
+		return err
+	}
+	return book.SetPanes(name, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+}
+
+// toRow converts header titles into a spreadsheet row.
+func toRow(titles []string) []any {
+	row := make([]any, len(titles))
+	for i, title := range titles {
+		row[i] = title
+	}
+	return row
+}